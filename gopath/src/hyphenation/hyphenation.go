@@ -0,0 +1,318 @@
+// package hyphenation finds hyphenation points in words with Liang's algorithm,
+// using TeX-style pattern files.
+package hyphenation
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SoftHyphen is the character inserted at hyphenation points.
+// It is invisible unless a line is broken there.
+const SoftHyphen = "\u00ad"
+
+// Patterns holds the hyphenation patterns and exceptions for a language.
+type Patterns struct {
+	// patterns maps the letters of a pattern to its values, one between each pair
+	// of letters and one at each end.
+	patterns map[string][]int
+	// exceptions maps a word to its hyphenation points.
+	exceptions map[string][]int
+	// maxLength is the length in letters of the longest pattern.
+	maxLength int
+}
+
+// commentRE matches TeX comments.
+var commentRE = regexp.MustCompile(`%.*`)
+
+// groupRE matches the \patterns and \hyphenation groups of a TeX pattern file.
+var groupRE = regexp.MustCompile(`\\(patterns|hyphenation)\s*\{([^}]*)\}`)
+
+// Parse reads patterns in the form of a TeX pattern file, which has a
+// \patterns{...} group and optionally a \hyphenation{...} group of exceptions.
+// Everything else in the file is ignored.
+func Parse(r io.Reader) (*Patterns, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := commentRE.ReplaceAllString(string(data), "")
+	p := &Patterns{patterns: make(map[string][]int), exceptions: make(map[string][]int)}
+	for _, group := range groupRE.FindAllStringSubmatch(text, -1) {
+		for _, item := range strings.Fields(group[2]) {
+			if group[1] == "patterns" {
+				p.addPattern(item)
+			} else {
+				p.addException(item)
+			}
+		}
+	}
+	if len(p.patterns) == 0 {
+		return nil, errors.New("No hyphenation patterns found")
+	}
+	return p, nil
+}
+
+// Load reads patterns from a TeX pattern file.
+func Load(filename string) (*Patterns, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// addPattern adds a pattern such as ".ach4" or "a1b".
+func (p *Patterns) addPattern(pattern string) {
+	var letters []rune
+	values := []int{0}
+	for _, r := range pattern {
+		if r >= '0' && r <= '9' {
+			values[len(values)-1] = int(r - '0')
+		} else {
+			letters = append(letters, unicode.ToLower(r))
+			values = append(values, 0)
+		}
+	}
+	p.patterns[string(letters)] = values
+	if len(letters) > p.maxLength {
+		p.maxLength = len(letters)
+	}
+}
+
+// addException adds an exception such as "ta-ble".
+func (p *Patterns) addException(word string) {
+	var letters []rune
+	var points []int
+	for _, r := range word {
+		if r == '-' {
+			points = append(points, len(letters))
+		} else {
+			letters = append(letters, unicode.ToLower(r))
+		}
+	}
+	p.exceptions[string(letters)] = points
+}
+
+// Hyphenate returns the hyphenation points of a word, as the number of
+// letters before each point. There are at least leftMin letters before the
+// first point and rightMin after the last.
+func (p *Patterns) Hyphenate(word string, leftMin, rightMin int) []int {
+	letters := []rune(word)
+	for i, r := range letters {
+		letters[i] = unicode.ToLower(r)
+	}
+	n := len(letters)
+	if leftMin < 1 {
+		leftMin = 1
+	}
+	if rightMin < 1 {
+		rightMin = 1
+	}
+	if n < leftMin+rightMin {
+		return nil
+	}
+	var candidates []int
+	if exception, ok := p.exceptions[string(letters)]; ok {
+		candidates = exception
+	} else {
+		// values[i] is the value of the position before letters[i-1] in ".word."
+		dotted := append(append([]rune{'.'}, letters...), '.')
+		values := make([]int, len(dotted)+1)
+		for start := range dotted {
+			for end := start + 1; end <= len(dotted) && end-start <= p.maxLength; end++ {
+				pattern, ok := p.patterns[string(dotted[start:end])]
+				if !ok {
+					continue
+				}
+				for i, v := range pattern {
+					if v > values[start+i] {
+						values[start+i] = v
+					}
+				}
+			}
+		}
+		for i := 1; i < n; i++ {
+			if values[i+1]%2 == 1 {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	var points []int
+	for _, i := range candidates {
+		if i >= leftMin && i <= n-rightMin {
+			points = append(points, i)
+		}
+	}
+	return points
+}
+
+// hyphenateWord returns a word with soft hyphens at its hyphenation points.
+func (p *Patterns) hyphenateWord(word string, leftMin, rightMin int) string {
+	var buf bytes.Buffer
+	p.writeWord(&buf, word, leftMin, rightMin)
+	return buf.String()
+}
+
+// writeWord writes a word to buf with soft hyphens at its hyphenation points.
+func (p *Patterns) writeWord(buf *bytes.Buffer, word string, leftMin, rightMin int) {
+	points := p.Hyphenate(word, leftMin, rightMin)
+	if len(points) == 0 {
+		buf.WriteString(word)
+		return
+	}
+	letters := []rune(word)
+	last := 0
+	for _, point := range points {
+		buf.WriteString(string(letters[last:point]))
+		buf.WriteString(SoftHyphen)
+		last = point
+	}
+	buf.WriteString(string(letters[last:]))
+}
+
+// HyphenateText inserts soft hyphens at the hyphenation points of the words of plain text.
+func (p *Patterns) HyphenateText(text string, leftMin, rightMin int) string {
+	var buf bytes.Buffer
+	p.writeText(&buf, text, leftMin, rightMin)
+	return buf.String()
+}
+
+// writeText writes plain text to buf with soft hyphens at the hyphenation points of its words.
+func (p *Patterns) writeText(buf *bytes.Buffer, text string, leftMin, rightMin int) {
+	for len(text) > 0 {
+		word := wordLength(text)
+		if word > 0 {
+			p.writeWord(buf, text[:word], leftMin, rightMin)
+			text = text[word:]
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text)
+		buf.WriteString(text[:size])
+		text = text[size:]
+	}
+}
+
+// HyphenateMarkup inserts soft hyphens at the hyphenation points of the words of
+// Pango markup, leaving tags and entities alone.
+func (p *Patterns) HyphenateMarkup(markup string, leftMin, rightMin int) string {
+	var buf bytes.Buffer
+	for len(markup) > 0 {
+		end := strings.IndexAny(markup, "<&")
+		if end < 0 {
+			end = len(markup)
+		}
+		p.writeText(&buf, markup[:end], leftMin, rightMin)
+		markup = markup[end:]
+		if len(markup) == 0 {
+			break
+		}
+		terminator := ">"
+		if markup[0] == '&' {
+			terminator = ";"
+		}
+		end = strings.Index(markup, terminator)
+		if end < 0 {
+			end = len(markup) - 1
+		}
+		buf.WriteString(markup[:end+1])
+		markup = markup[end+1:]
+	}
+	return buf.String()
+}
+
+// wordLength returns the length in bytes of the run of letters at the start of text.
+func wordLength(text string) int {
+	n := 0
+	for n < len(text) {
+		r, size := utf8.DecodeRuneInString(text[n:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// patternDir is the directory holding the pattern files.
+var patternDir string
+
+// cache holds the patterns that have been loaded, by file name.
+var cache = make(map[string]*Patterns)
+var cacheLock sync.Mutex
+
+// SetPatternDir sets the directory holding the pattern files.
+// Patterns for a language are in a file named hyph-{language}.tex,
+// like the files of the TeX hyph-utf8 project.
+func SetPatternDir(dir string) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	patternDir = dir
+	cache = make(map[string]*Patterns)
+}
+
+// languageAliases maps languages to the names of their pattern files,
+// where those differ.
+var languageAliases = map[string]string{
+	"en": "en-us",
+	"de": "de-1996",
+}
+
+// patternNameRE matches the language part of a pattern file name.
+var patternNameRE = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// patternNames returns the candidate pattern file names for a BCP 47 language tag,
+// from the most to the least specific. A tag with anything but letters, digits,
+// hyphens and underscores has none, so that no name reaches outside the pattern directory.
+func patternNames(language string) []string {
+	var names []string
+	tag := strings.ToLower(strings.Replace(language, "_", "-", -1))
+	if !patternNameRE.MatchString(tag) {
+		return nil
+	}
+	for tag != "" {
+		names = append(names, tag)
+		if alias, ok := languageAliases[tag]; ok {
+			names = append(names, alias)
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return names
+}
+
+// ForLanguage returns the patterns for a BCP 47 language tag, such as "en-US".
+// If there are no patterns for the tag, it tries less specific ones.
+func ForLanguage(language string) (*Patterns, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	for _, name := range patternNames(language) {
+		filename := path.Join(patternDir, "hyph-"+name+".tex")
+		if p, ok := cache[filename]; ok {
+			return p, nil
+		}
+		if _, err := os.Stat(filename); err != nil {
+			continue
+		}
+		p, err := Load(filename)
+		if err != nil {
+			return nil, err
+		}
+		cache[filename] = p
+		return p, nil
+	}
+	return nil, errors.New("No hyphenation patterns for language " + language)
+}
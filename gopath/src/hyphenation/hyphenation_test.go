@@ -0,0 +1,97 @@
+package hyphenation
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// show returns text with soft hyphens made visible.
+func show(text string) string {
+	return strings.Replace(text, SoftHyphen, "-", -1)
+}
+
+func TestHyphenate(t *testing.T) {
+	p, err := Load("testdata/hyph-xx.tex")
+	if err != nil {
+		t.Fatalf("could not load patterns: %q", err.Error())
+	}
+	type data struct {
+		Word     string
+		LeftMin  int
+		RightMin int
+		Expected string
+	}
+	testData := []data{data{"hyphenation", 2, 3, "hy-phen-ation"},
+		data{"Hyphenation", 3, 3, "Hyphen-ation"},
+		data{"hyphenation", 2, 6, "hy-phenation"},
+		data{"table", 1, 1, "ta-ble"},
+		data{"exception", 2, 2, "ex-cep-tion"},
+		data{"ton", 1, 1, "ton"}}
+	for _, d := range testData {
+		if s := show(p.hyphenateWord(d.Word, d.LeftMin, d.RightMin)); s != d.Expected {
+			t.Errorf("%q hyphenated as %q", d.Word, s)
+		}
+	}
+	if s := show(p.HyphenateText("A table, “hyphenation”!", 2, 2)); s != "A ta-ble, “hy-phen-ation”!" {
+		t.Errorf("text hyphenated as %q", s)
+	}
+	markup := `<span font_family="hyphenation">table</span> &amp; hyphenation`
+	if s := show(p.HyphenateMarkup(markup, 2, 2)); s != `<span font_family="hyphenation">ta-ble</span> &amp; hy-phen-ation` {
+		t.Errorf("markup hyphenated as %q", s)
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	SetPatternDir("testdata")
+	defer SetPatternDir("")
+	p, err := ForLanguage("xx-YY")
+	if err != nil {
+		t.Fatalf("could not find patterns: %q", err.Error())
+	}
+	if p2, _ := ForLanguage("xx"); p2 != p {
+		t.Errorf("patterns were not cached")
+	}
+	if _, err := ForLanguage("zz"); err == nil {
+		t.Errorf("found patterns for a missing language")
+	}
+	for _, language := range []string{"xx/../xx", "../testdata/xx", "xx.", "xx yy", ""} {
+		if _, err := ForLanguage(language); err == nil {
+			t.Errorf("found patterns for %q", language)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`\hyphenation{ta-ble}`)); err == nil {
+		t.Errorf("no error for a file without patterns")
+	}
+}
+
+func TestBundledPatterns(t *testing.T) {
+	SetPatternDir("../../../hyphenation")
+	defer SetPatternDir("")
+	type data struct {
+		Language   string
+		File       string
+		Hyphenated string
+	}
+	testData := []data{data{"en-US", "hyph-en-us.tex", "hy-phen-a-tion"},
+		data{"de", "hyph-de-1996.tex", "Sil-ben-tren-nung"},
+		data{"fr", "hyph-fr.tex", "an-ti-cons-ti-tu-tion-nel-le-ment"}}
+	for _, d := range testData {
+		if _, err := os.Stat("../../../hyphenation/" + d.File); err != nil {
+			t.Errorf("%s is not bundled: %q", d.File, err.Error())
+			continue
+		}
+		p, err := ForLanguage(d.Language)
+		if err != nil {
+			t.Errorf("could not load patterns for %s: %q", d.Language, err.Error())
+			continue
+		}
+		word := strings.Replace(d.Hyphenated, "-", "", -1)
+		if s := strings.Replace(p.hyphenateWord(word, 2, 3), SoftHyphen, "-", -1); s != d.Hyphenated {
+			t.Errorf("%q was hyphenated as %q with the patterns for %s", word, s, d.Language)
+		}
+	}
+}
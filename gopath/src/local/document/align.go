@@ -0,0 +1,56 @@
+package document
+
+import (
+	"errors"
+	"strings"
+)
+
+// Alignments, which say how the lines of a paragraph are set. The lines of a justified
+// paragraph fill the text width, except the last, which is set by the last line alignment.
+// AlignJustifyAll justifies the last line as well. AlignStart, only for last lines, sets
+// a line at the side its paragraph starts from: the left, or the right for right-to-left text.
+const (
+	AlignJustify    = "justify"
+	AlignLeft       = "left"
+	AlignRight      = "right"
+	AlignCenter     = "center"
+	AlignJustifyAll = "justify-all-lines"
+	AlignStart      = "start"
+)
+
+// Alignments and LastLineAlignments are the alignments of paragraphs and of the last
+// lines of justified paragraphs.
+var (
+	Alignments         = []string{AlignJustify, AlignLeft, AlignRight, AlignCenter, AlignJustifyAll}
+	LastLineAlignments = []string{AlignStart, AlignLeft, AlignRight, AlignCenter, AlignJustify}
+)
+
+// parseAlignment returns the alignment and last line alignment of an \align command.
+// Empty arguments are left empty, for those of the document.
+func parseAlignment(args []string) (alignment, lastLine string, err error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", "", errors.New(`\align takes an alignment, and optionally one for the last line`)
+	}
+	alignment = strings.TrimSpace(args[0])
+	if !oneOf(alignment, Alignments) {
+		return "", "", errors.New("Unknown alignment " + alignment)
+	}
+	if len(args) == 2 {
+		lastLine = strings.TrimSpace(args[1])
+		if !oneOf(lastLine, LastLineAlignments) {
+			return "", "", errors.New("Unknown last line alignment " + lastLine)
+		}
+	}
+	return alignment, lastLine, nil
+}
+
+// validateAlignment checks the alignment and last line alignment of a document.
+func (doc *Document) validateAlignment() error {
+	if !oneOf(doc.Alignment, Alignments) {
+		return errors.New("Unknown alignment " + doc.Alignment)
+	}
+	if !oneOf(doc.LastLineAlignment, LastLineAlignments) {
+		return errors.New("Unknown last line alignment " + doc.LastLineAlignment)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestValidateAlignment(t *testing.T) {
+	type data struct {
+		Alignment, LastLine string
+		Ok                  bool
+	}
+	var testData []data = []data{data{"", "", true},
+		data{"justify", "start", true},
+		data{"left", "", true},
+		data{"justify-all-lines", "center", true},
+		data{"justify", "justify", true},
+		data{"ragged-right", "", false},
+		data{"justify", "justify-all-lines", false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.Alignment, doc.LastLineAlignment = d.Alignment, d.LastLine
+		if err := doc.Validate(); (err == nil) != d.Ok {
+			t.Errorf("Validate() with %v returned %v", d, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestColors(t *testing.T) {
+	type data struct {
+		Str     string
+		Normstr string
+		R, G, B float64
+		Ok      bool
+	}
+	var testData []data = []data{data{"#FF0000", "#ff0000", 1, 0, 0, true},
+		data{"#0f0", "#0f0", 0, 1, 0, true},
+		data{"rgb(0, 0, 255)", "rgb(0,0,255)", 0, 0, 1, true},
+		data{"rgb(100%, 50%, 0%)", "rgb(100%,50%,0%)", 1, 0.5, 0, true},
+		data{"Navy", "navy", 0, 0, 128.0 / 255, true},
+		data{"gray(0.25)", "gray(0.25)", 0.25, 0.25, 0.25, true},
+		data{"grey(50%)", "gray(50%)", 0.5, 0.5, 0.5, true},
+		data{"#12345", "", 0, 0, 0, false},
+		data{"rgb(256, 0, 0)", "", 0, 0, 0, false},
+		data{"gray(2)", "", 0, 0, 0, false},
+		data{"reddish", "", 0, 0, 0, false}}
+
+	for _, d := range testData {
+		color, err := ColorFromString(d.Str)
+		if err != nil {
+			if d.Ok {
+				t.Errorf("color failed for %q\n", d.Str)
+			}
+		} else if !d.Ok {
+			t.Errorf("color didn't fail for %q\n", d.Str)
+		} else {
+			if color.String() != d.Normstr {
+				t.Errorf("color for %q has string %q\n", d.Str, color.String())
+			}
+			if r, g, b := color.RGB(); r != d.R || g != d.G || b != d.B {
+				t.Errorf("color for %q is %g, %g, %g\n", d.Str, r, g, b)
+			}
+		}
+	}
+}
+
+func TestColorJSON(t *testing.T) {
+	var doc struct {
+		Text  Color
+		Page  Color
+		Blank Color
+	}
+	if err := json.Unmarshal([]byte(`{"Text": "Teal", "Page": "#fff", "Blank": ""}`), &doc); err != nil {
+		t.Fatalf("could not unmarshal colors: %v", err)
+	}
+	if doc.Text.String() != "teal" || doc.Page.String() != "#fff" || !doc.Blank.IsZero() {
+		t.Errorf("unmarshaled colors %q, %q and %q", doc.Text, doc.Page, doc.Blank)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil || string(data) != `{"Text":"teal","Page":"#fff","Blank":""}` {
+		t.Errorf("marshaled colors as %s (%v)", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Text": "not a color"}`), &doc); err == nil {
+		t.Errorf("unmarshaled an invalid color")
+	}
+}
+
+func TestDefaultColors(t *testing.T) {
+	doc := DefaultDocument()
+	if doc.TextColor.String() != "black" || !doc.PageColor.IsZero() {
+		t.Errorf("default colors are %q and %q", doc.TextColor, doc.PageColor)
+	}
+}
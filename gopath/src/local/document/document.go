@@ -7,6 +7,7 @@ import (
 	"errors"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 // Length represents a page-length value.
@@ -185,9 +186,38 @@ func (l *Length) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// Text formats, which say how a document's Text is interpreted.
+const (
+	PlainText   = "plain"
+	PangoMarkup = "pango-markup"
+	Markdown    = "markdown"
+)
+
+// Line breaking methods, which say how paragraphs are broken into lines.
+const (
+	GreedyBreaking  = "greedy"
+	OptimalBreaking = "optimal"
+)
+
+// RunningHead is the text of a header or footer, in slots at the left, center
+// and right of the page. The text may use the placeholders {page}, {pages},
+// {title} and {date}.
+type RunningHead struct {
+	Left   string
+	Center string
+	Right  string
+}
+
 // Document encapsulates the defining properties of a document.
 type Document struct {
-	Font         string
+	Font string
+	// FontWeight is a name in FontWeights or a number from 100 to 1000.
+	// FontStyle, FontStretch and FontVariant are names in FontStyles,
+	// FontStretches and FontVariants. Empty values are normal.
+	FontWeight   string
+	FontStyle    string
+	FontStretch  string
+	FontVariant  string
 	Text         string
 	FontSize     Length
 	BaselineSkip Length
@@ -197,6 +227,103 @@ type Document struct {
 	BottomMargin Length
 	PageHeight   Length
 	PageWidth    Length
+	// FontFeatures are OpenType feature settings, such as "liga=1, onum=1, smcp",
+	// as parsed by ParseFontFeatures. LetterSpacing is extra space between letters.
+	FontFeatures  string
+	LetterSpacing Length
+	// TextFormat is the format of Text, PlainText, PangoMarkup or Markdown.
+	// An empty format is PlainText.
+	TextFormat string
+	// ParIndent is the indentation of the first line of each paragraph,
+	// except the first, which is indented by FirstParagraphIndent.
+	ParIndent            Length
+	FirstParagraphIndent Length
+	// ParSkip is the extra space between paragraphs.
+	ParSkip Length
+	// Alignment is one of Alignments, and LastLineAlignment, one of LastLineAlignments,
+	// is that of the last line of justified paragraphs. Empty alignments are
+	// AlignJustify and AlignStart. \align commands in the text override them.
+	Alignment         string
+	LastLineAlignment string
+	// Language is the BCP 47 tag of the document's language, such as "en-US".
+	// Direction is LeftToRight, RightToLeft or AutoDirection; an empty direction
+	// is AutoDirection. Right-to-left paragraphs are indented and aligned from the
+	// right. A RightToLeft document is mirrored: its columns run from right to left,
+	// LeftMargin is at the right of the page, and the Left and Right slots of its
+	// header and footer are swapped.
+	Language  string
+	Direction string
+	// Hyphenate turns on hyphenation, with at least LeftHyphenMin letters
+	// before a hyphen and RightHyphenMin after it. Minimums of 0 are
+	// DefaultLeftHyphenMin and DefaultRightHyphenMin.
+	Hyphenate      bool
+	LeftHyphenMin  int
+	RightHyphenMin int
+	// LineBreaking is GreedyBreaking, which fills each line in turn, or
+	// OptimalBreaking, which chooses the breaks of a whole paragraph at once.
+	// An empty method is GreedyBreaking.
+	LineBreaking string
+	// Tolerance is the greatest badness allowed for a line with optimal breaking,
+	// and Looseness the number of lines to add to (or remove from) each paragraph.
+	Tolerance int
+	Looseness int
+	// HyphenPenalty and ExHyphenPenalty are the costs of breaking a line at a
+	// hyphenation point and at an explicit hyphen, with optimal breaking.
+	HyphenPenalty   int
+	ExHyphenPenalty int
+	// Title is the title of the document. It, Author, Subject, Keywords and
+	// Creator are also set as the metadata of PDF files.
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+	// Created and Modified are the times the document was added to the
+	// database and last updated there. They are set by the database, which ignores
+	// those of documents it is given.
+	Created  time.Time
+	Modified time.Time
+	// Header and Footer are set on every page, with their baselines HeaderSkip
+	// above the top margin and FooterSkip below the lowest baseline of the text.
+	// SuppressFirstPageHeads leaves them off the first page.
+	Header                 RunningHead
+	Footer                 RunningHead
+	HeaderSkip             Length
+	FooterSkip             Length
+	SuppressFirstPageHeads bool
+	// Columns is the number of columns of text on each page, separated by ColumnSep.
+	// ColumnRule draws rules between the columns. The columns of the last page
+	// are balanced.
+	Columns    int
+	ColumnSep  Length
+	ColumnRule bool
+	// Widows and Orphans are the fewest lines of a paragraph to leave alone at the
+	// top and at the bottom of a page or column. WidowPenalty and OrphanPenalty are
+	// the costs of leaving fewer, from 0 to 10000, which means never.
+	Widows        int
+	Orphans       int
+	WidowPenalty  int
+	OrphanPenalty int
+	// TextColor is the color of the text, PageColor the color of the background
+	// of the pages, RuleColor the color of rules, such as the one above the notes,
+	// and ColumnRuleColor the color of the rules between columns. Unset colors
+	// are black, except PageColor, which leaves the pages blank.
+	TextColor       Color
+	PageColor       Color
+	RuleColor       Color
+	ColumnRuleColor Color
+	// Watermarks are stamped on the pages, such as "DRAFT" across the review copies.
+	Watermarks []Watermark
+	// BaselineGrid puts the lines of body text on a grid of lines BaselineSkip apart,
+	// so that they line up across columns and facing pages, and ShowBaselineGrid
+	// draws the grid on the pages, for checking.
+	BaselineGrid     bool
+	ShowBaselineGrid bool
+	// Endnotes lists the notes at the end of the document, rather than at the
+	// bottom of the pages that refer to them, under a heading of EndnotesTitle,
+	// or of DefaultEndnotesTitle if that is empty.
+	Endnotes      bool
+	EndnotesTitle string
 	// Id is the document identifier. It is serialized to JSON is "id", 
 	// and omitted if empty.
 	Id db.Id `json:"id,omitempty" bson:"id,omitempty"`
@@ -206,7 +333,14 @@ type Document struct {
 func DefaultDocument() *Document {
 	doc := Document{}
 	doc.Font = "Adobe Garamond Pro"
+	doc.FontWeight = "normal"
+	doc.FontStyle = "normal"
+	doc.FontStretch = "normal"
+	doc.FontVariant = "normal"
+	doc.FontFeatures = ""
+	doc.LetterSpacing = LengthFromPoints(0)
 	doc.Text = "Lorem Ipsum"
+	doc.TextFormat = PlainText
 	doc.FontSize = LengthFromPoints(12)
 	doc.BaselineSkip = LengthFromPoints(15)
 	doc.LeftMargin = LengthFromPoints(72)
@@ -215,14 +349,78 @@ func DefaultDocument() *Document {
 	doc.BottomMargin = LengthFromPoints(72)
 	doc.PageHeight, _ = LengthFromString(`11in`)
 	doc.PageWidth, _ = LengthFromString(`8.5"`)
+	doc.ParIndent = LengthFromPoints(18)
+	doc.FirstParagraphIndent = LengthFromPoints(0)
+	doc.ParSkip = LengthFromPoints(0)
+	doc.Alignment = AlignJustify
+	doc.LastLineAlignment = AlignStart
+	doc.Language = "en-US"
+	doc.Direction = AutoDirection
+	doc.Hyphenate = false
+	doc.LeftHyphenMin = DefaultLeftHyphenMin
+	doc.RightHyphenMin = DefaultRightHyphenMin
+	doc.LineBreaking = GreedyBreaking
+	doc.Tolerance = 200
+	doc.Looseness = 0
+	doc.HyphenPenalty = 50
+	doc.ExHyphenPenalty = 50
+	doc.HeaderSkip = LengthFromPoints(24)
+	doc.FooterSkip = LengthFromPoints(30)
+	doc.Columns = 1
+	doc.ColumnSep = LengthFromPoints(18)
+	doc.ColumnRule = false
+	doc.Widows = 2
+	doc.Orphans = 2
+	doc.WidowPenalty = 150
+	doc.OrphanPenalty = 150
+	doc.Endnotes = false
+	doc.EndnotesTitle = DefaultEndnotesTitle
+	doc.BaselineGrid = false
+	doc.ShowBaselineGrid = false
+	doc.TextColor, _ = ColorFromString("black")
+	doc.RuleColor, _ = ColorFromString("black")
+	doc.ColumnRuleColor, _ = ColorFromString("black")
 	return &doc
 }
 
+// Validate checks the settings of a document that are not checked when it is parsed.
+func (doc *Document) Validate() error {
+	switch doc.LineBreaking {
+	case "", GreedyBreaking, OptimalBreaking:
+	default:
+		return errors.New("Unknown line breaking method " + doc.LineBreaking)
+	}
+	if doc.Tolerance < 0 {
+		return errors.New("Tolerance must not be negative")
+	}
+	if doc.WidowPenalty < 0 || doc.OrphanPenalty < 0 {
+		return errors.New("Widow and orphan penalties must not be negative")
+	}
+	if doc.Columns < 0 {
+		return errors.New("Columns must not be negative")
+	}
+	if err := doc.validateWatermarks(); err != nil {
+		return err
+	}
+	if err := doc.validateAlignment(); err != nil {
+		return err
+	}
+	if err := doc.validateLanguage(); err != nil {
+		return err
+	}
+	if err := doc.validateHyphenation(); err != nil {
+		return err
+	}
+	return doc.validateFont()
+}
+
 type DB interface {
 	Add(doc *Document) error
 	Update(doc *Document) error
 	Fetch(id db.Id) (Document, error)
 	Delete(id db.Id) error
+	AddImage(img *Image) error
+	FetchImage(id db.Id) (Image, error)
 	DeleteAll() error
 	DropDB() error
 	Close()
@@ -0,0 +1,31 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type data struct {
+		LineBreaking string
+		Tolerance    int
+		Columns      int
+		Ok           bool
+	}
+	var testData []data = []data{data{"", 200, 1, true},
+		data{GreedyBreaking, 200, 0, true},
+		data{OptimalBreaking, 10000, 3, true},
+		data{"fancy", 200, 1, false},
+		data{OptimalBreaking, -1, 1, false},
+		data{GreedyBreaking, 200, -2, false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.LineBreaking = d.LineBreaking
+		doc.Tolerance = d.Tolerance
+		doc.Columns = d.Columns
+		err := doc.Validate()
+		if (err == nil) != d.Ok {
+			t.Errorf("Validate() with %q, %d, %d returned %v", d.LineBreaking, d.Tolerance, d.Columns, err)
+		}
+	}
+}
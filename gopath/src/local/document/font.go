@@ -0,0 +1,104 @@
+package document
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FontWeights are the names of font weights, and their numeric values.
+var FontWeights = map[string]int{
+	"thin":       100,
+	"ultralight": 200,
+	"light":      300,
+	"semilight":  350,
+	"book":       380,
+	"normal":     400,
+	"medium":     500,
+	"semibold":   600,
+	"bold":       700,
+	"ultrabold":  800,
+	"heavy":      900,
+	"ultraheavy": 1000,
+}
+
+// FontStyles, FontStretches and FontVariants are the names of the font styles,
+// stretches and variants, from narrowest to widest for stretches.
+var (
+	FontStyles    = []string{"normal", "italic", "oblique"}
+	FontStretches = []string{"ultra-condensed", "extra-condensed", "condensed", "semi-condensed", "normal",
+		"semi-expanded", "expanded", "extra-expanded", "ultra-expanded"}
+	FontVariants = []string{"normal", "small-caps"}
+)
+
+// ParseFontWeight returns the numeric value of a font weight, given by name or
+// as a number from 100 to 1000. An empty weight is normal.
+func ParseFontWeight(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return FontWeights["normal"], nil
+	}
+	if weight, ok := FontWeights[s]; ok {
+		return weight, nil
+	}
+	weight, err := strconv.Atoi(s)
+	if err != nil || weight < 100 || weight > 1000 {
+		return 0, errors.New("Invalid font weight " + s)
+	}
+	return weight, nil
+}
+
+// fontFeatureRE matches an OpenType feature setting: a four-letter tag, turned on
+// with a + or on its own, off with a -, or set to a value, such as 2 for an alternate.
+var fontFeatureRE = regexp.MustCompile(`^([+-][A-Za-z0-9]{4}|[A-Za-z0-9]{4}(=[0-9]+)?)$`)
+
+// ParseFontFeatures returns the OpenType feature settings of a comma-separated list,
+// such as "liga=1, onum=1, smcp", without spaces. An empty list has no settings.
+func ParseFontFeatures(s string) ([]string, error) {
+	var features []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.Join(strings.Fields(f), "")
+		if f == "" {
+			continue
+		}
+		if !fontFeatureRE.MatchString(f) {
+			return nil, errors.New("Invalid font feature " + f)
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// oneOf returns true if s is empty or one of names.
+func oneOf(s string, names []string) bool {
+	if s == "" {
+		return true
+	}
+	for _, name := range names {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFont checks the font weight, style, stretch, variant and features of a document.
+func (doc *Document) validateFont() error {
+	if _, err := ParseFontWeight(doc.FontWeight); err != nil {
+		return err
+	}
+	if !oneOf(doc.FontStyle, FontStyles) {
+		return errors.New("Unknown font style " + doc.FontStyle)
+	}
+	if !oneOf(doc.FontStretch, FontStretches) {
+		return errors.New("Unknown font stretch " + doc.FontStretch)
+	}
+	if !oneOf(doc.FontVariant, FontVariants) {
+		return errors.New("Unknown font variant " + doc.FontVariant)
+	}
+	if _, err := ParseFontFeatures(doc.FontFeatures); err != nil {
+		return err
+	}
+	return nil
+}
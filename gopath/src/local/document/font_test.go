@@ -0,0 +1,71 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFontWeight(t *testing.T) {
+	type data struct {
+		Weight string
+		Value  int
+		Ok     bool
+	}
+	var testData []data = []data{data{"", 400, true},
+		data{"normal", 400, true},
+		data{" SemiBold ", 600, true},
+		data{"550", 550, true},
+		data{"1000", 1000, true},
+		data{"50", 0, false},
+		data{"fat", 0, false},
+	}
+	for _, d := range testData {
+		value, err := ParseFontWeight(d.Weight)
+		if (err == nil) != d.Ok || value != d.Value {
+			t.Errorf("ParseFontWeight(%q) returned %d, %v", d.Weight, value, err)
+		}
+	}
+}
+
+func TestValidateFont(t *testing.T) {
+	type data struct {
+		Weight, Style, Stretch, Variant string
+		Ok                              bool
+	}
+	var testData []data = []data{data{"", "", "", "", true},
+		data{"semibold", "italic", "condensed", "small-caps", true},
+		data{"bold", "slanted", "normal", "normal", false},
+		data{"bold", "normal", "narrow", "normal", false},
+		data{"bold", "normal", "normal", "all-caps", false},
+		data{"heavier", "normal", "normal", "normal", false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.FontWeight, doc.FontStyle, doc.FontStretch, doc.FontVariant = d.Weight, d.Style, d.Stretch, d.Variant
+		if err := doc.Validate(); (err == nil) != d.Ok {
+			t.Errorf("Validate() with %v returned %v", d, err)
+		}
+	}
+}
+
+func TestParseFontFeatures(t *testing.T) {
+	type data struct {
+		Features string
+		Parsed   string
+		Ok       bool
+	}
+	var testData []data = []data{data{"", "", true},
+		data{"liga=1, onum=1, smcp", "liga=1 onum=1 smcp", true},
+		data{" -kern ,+ss01,salt = 2,", "-kern +ss01 salt=2", true},
+		data{"liga=on", "", false},
+		data{"lig", "", false},
+		data{"+liga=1", "", false},
+		data{"\"liga\" 1", "", false},
+	}
+	for _, d := range testData {
+		features, err := ParseFontFeatures(d.Features)
+		if (err == nil) != d.Ok || strings.Join(features, " ") != d.Parsed {
+			t.Errorf("ParseFontFeatures(%q) returned %q, %v", d.Features, features, err)
+		}
+	}
+}
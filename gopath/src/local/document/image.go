@@ -0,0 +1,73 @@
+package document
+
+import (
+	"bytes"
+	"db"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strconv"
+)
+
+// Image is an uploaded image, which documents can refer to by its Id.
+type Image struct {
+	// ContentType is "image/png" or "image/jpeg".
+	ContentType string
+	Data        []byte
+	Id          db.Id `json:"id,omitempty" bson:"id,omitempty"`
+	// Width and Height are the size of the image in pixels.
+	Width  int
+	Height int
+}
+
+func (img Image) ObjectId() db.Id {
+	return img.Id
+}
+
+func (img *Image) SetObjectId(id db.Id) {
+	img.Id = id
+}
+
+// ImageTypes are the content types of the images that can be used.
+var ImageTypes = []string{"image/png", "image/jpeg"}
+
+// MaxImagePixels is the most pixels an image can have, as images are decoded
+// whole to be drawn.
+const MaxImagePixels = 25000000
+
+// NewImage returns an Image for image data, with its type detected from the data
+// and its size read from it. It fails if the data is not a PNG or JPEG image, or
+// if the image has more than MaxImagePixels.
+func NewImage(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	for _, t := range ImageTypes {
+		if contentType == t {
+			img := &Image{ContentType: contentType, Data: data}
+			if err := img.Measure(); err != nil {
+				return nil, err
+			}
+			return img, nil
+		}
+	}
+	return nil, errors.New("Images must be PNG or JPEG, not " + contentType)
+}
+
+// Measure sets the size of an image from its data, unless it is already set,
+// as it is for images made by NewImage. It fails if the data cannot be read,
+// or if the image has more than MaxImagePixels.
+func (img *Image) Measure() error {
+	if img.Width == 0 || img.Height == 0 {
+		config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			return errors.New("Could not read image: " + err.Error())
+		}
+		img.Width, img.Height = config.Width, config.Height
+	}
+	if img.Width < 1 || img.Height < 1 || float64(img.Width)*float64(img.Height) > MaxImagePixels {
+		return errors.New("Images can have at most " + strconv.Itoa(MaxImagePixels) + " pixels, not " +
+			strconv.Itoa(img.Width) + "x" + strconv.Itoa(img.Height))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encodeImage returns a blank image of the given size, encoded as PNG or JPEG.
+func encodeImage(contentType string, width, height int) []byte {
+	var buf bytes.Buffer
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if contentType == "image/png" {
+		png.Encode(&buf, img)
+	} else {
+		jpeg.Encode(&buf, img, nil)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG returns a small PNG image whose header claims it is width by height.
+func hugePNG(width, height uint32) []byte {
+	data := encodeImage("image/png", 1, 1)
+	// The IHDR chunk follows the 8-byte signature: its length, type, data and CRC.
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+func TestNewImage(t *testing.T) {
+	type data struct {
+		Data          []byte
+		ContentType   string
+		Width, Height int
+		Ok            bool
+	}
+	var testData []data = []data{data{encodeImage("image/png", 3, 2), "image/png", 3, 2, true},
+		data{encodeImage("image/jpeg", 5, 4), "image/jpeg", 5, 4, true},
+		data{[]byte("\x89PNG\r\n\x1a\n...."), "", 0, 0, false},
+		data{hugePNG(60000, 60000), "", 0, 0, false},
+		data{hugePNG(5000, 5000), "image/png", 5000, 5000, true},
+		data{[]byte("GIF89a...."), "", 0, 0, false},
+		data{[]byte("Hello"), "", 0, 0, false},
+	}
+	for i, d := range testData {
+		img, err := NewImage(d.Data)
+		if (err == nil) != d.Ok {
+			t.Errorf("NewImage of image %d returned error %v", i, err)
+			continue
+		}
+		if err == nil && (img.ContentType != d.ContentType || img.Width != d.Width || img.Height != d.Height) {
+			t.Errorf("image %d has type %q and size %dx%d", i, img.ContentType, img.Width, img.Height)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	img := Image{ContentType: "image/png", Data: encodeImage("image/png", 7, 3)}
+	if err := img.Measure(); err != nil || img.Width != 7 || img.Height != 3 {
+		t.Errorf("image was measured as %dx%d, with error %v", img.Width, img.Height, err)
+	}
+	img = Image{ContentType: "image/png", Data: hugePNG(60000, 60000)}
+	if err := img.Measure(); err == nil {
+		t.Errorf("no error for an image of %dx%d", img.Width, img.Height)
+	}
+}
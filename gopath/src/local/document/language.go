@@ -0,0 +1,69 @@
+package document
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// Text directions, which say which way paragraphs run. With AutoDirection, each
+// paragraph runs the way of its first letter, such as right to left for Hebrew.
+const (
+	LeftToRight   = "ltr"
+	RightToLeft   = "rtl"
+	AutoDirection = "auto"
+)
+
+// languageTagRE matches a BCP 47 language tag: a language, then an optional script,
+// region and variants, and then optional extensions and private use subtags,
+// such as "en-US", "sr-Latn-RS" or "de-CH-1996".
+var languageTagRE = regexp.MustCompile(`^(?i:[a-z]{2,3}(-[a-z]{4})?(-([a-z]{2}|[0-9]{3}))?` +
+	`(-([a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*(-[0-9a-wyz](-[a-z0-9]{2,8})+)*(-x(-[a-z0-9]{1,8})+)?)$`)
+
+// ValidLanguageTag returns true if tag is a well-formed BCP 47 language tag.
+func ValidLanguageTag(tag string) bool {
+	return languageTagRE.MatchString(tag)
+}
+
+// validateLanguage checks the language and direction of a document.
+// An empty language or direction is allowed, and an empty direction is AutoDirection.
+func (doc *Document) validateLanguage() error {
+	if doc.Language != "" && !ValidLanguageTag(doc.Language) {
+		return errors.New("Invalid language tag " + doc.Language)
+	}
+	switch doc.Direction {
+	case "", LeftToRight, RightToLeft, AutoDirection:
+	default:
+		return errors.New("Unknown text direction " + doc.Direction)
+	}
+	return nil
+}
+
+// The least numbers of letters before and after a hyphen of documents that leave
+// them 0, and the most that may be asked for.
+const (
+	DefaultLeftHyphenMin  = 2
+	DefaultRightHyphenMin = 3
+	MaxHyphenMin          = 10
+)
+
+// HyphenMins returns the least numbers of letters before and after a hyphen.
+func (doc *Document) HyphenMins() (left, right int) {
+	left, right = doc.LeftHyphenMin, doc.RightHyphenMin
+	if left == 0 {
+		left = DefaultLeftHyphenMin
+	}
+	if right == 0 {
+		right = DefaultRightHyphenMin
+	}
+	return left, right
+}
+
+// validateHyphenation checks the hyphenation settings of a document.
+func (doc *Document) validateHyphenation() error {
+	if doc.LeftHyphenMin < 0 || doc.LeftHyphenMin > MaxHyphenMin ||
+		doc.RightHyphenMin < 0 || doc.RightHyphenMin > MaxHyphenMin {
+		return errors.New("Hyphenation minimums must be from 0 to " + strconv.Itoa(MaxHyphenMin))
+	}
+	return nil
+}
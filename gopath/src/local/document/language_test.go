@@ -0,0 +1,79 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestValidLanguageTag(t *testing.T) {
+	type data struct {
+		Tag string
+		Ok  bool
+	}
+	var testData []data = []data{data{"en", true},
+		data{"en-US", true},
+		data{"ar-EG", true},
+		data{"he", true},
+		data{"sr-Latn-RS", true},
+		data{"de-CH-1996", true},
+		data{"es-419", true},
+		data{"zh-Hant-TW-x-private", true},
+		data{"en-u-ca-gregory", true},
+		data{"english", false},
+		data{"en_US", false},
+		data{"en-", false},
+		data{"e", false},
+	}
+	for _, d := range testData {
+		if ValidLanguageTag(d.Tag) != d.Ok {
+			t.Errorf("ValidLanguageTag(%q) was %v", d.Tag, !d.Ok)
+		}
+	}
+}
+
+func TestValidateLanguage(t *testing.T) {
+	type data struct {
+		Language, Direction string
+		Ok                  bool
+	}
+	var testData []data = []data{data{"", "", true},
+		data{"he-IL", "rtl", true},
+		data{"en-US", "ltr", true},
+		data{"ar", "auto", true},
+		data{"ar", "right-to-left", false},
+		data{"arabic", "rtl", false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.Language, doc.Direction = d.Language, d.Direction
+		if err := doc.Validate(); (err == nil) != d.Ok {
+			t.Errorf("Validate() with %v returned %v", d, err)
+		}
+	}
+}
+
+func TestHyphenMins(t *testing.T) {
+	type data struct {
+		Left, Right       int
+		MinLeft, MinRight int
+		Ok                bool
+	}
+	var testData []data = []data{data{2, 3, 2, 3, true},
+		data{0, 0, DefaultLeftHyphenMin, DefaultRightHyphenMin, true},
+		data{1, 0, 1, DefaultRightHyphenMin, true},
+		data{MaxHyphenMin, MaxHyphenMin, MaxHyphenMin, MaxHyphenMin, true},
+		data{-1, 3, 0, 0, false},
+		data{2, -3, 0, 0, false},
+		data{MaxHyphenMin + 1, 3, 0, 0, false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.LeftHyphenMin, doc.RightHyphenMin = d.Left, d.Right
+		if err := doc.Validate(); (err == nil) != d.Ok {
+			t.Errorf("Validate() with minimums %d and %d returned %v", d.Left, d.Right, err)
+			continue
+		}
+		if left, right := doc.HyphenMins(); d.Ok && (left != d.MinLeft || right != d.MinRight) {
+			t.Errorf("HyphenMins() with %d and %d returned %d and %d", d.Left, d.Right, left, right)
+		}
+	}
+}
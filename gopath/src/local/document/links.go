@@ -0,0 +1,143 @@
+package document
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LinkStart and LinkEnd mark the text of a link in the text of a block.
+const (
+	LinkStart = "\ufff9"
+	LinkEnd   = "\ufffb"
+)
+
+// Link is the target of a link in a block, a URL or the Anchor of a heading after a '#'.
+type Link struct {
+	Target string
+}
+
+// Internal returns the anchor of the heading a link is to, and false if it is not to a heading.
+func (l Link) Internal() (anchor string, ok bool) {
+	if strings.HasPrefix(l.Target, "#") {
+		return l.Target[1:], true
+	}
+	return "", false
+}
+
+// linkRE matches a link, \link{target}{text}.
+var linkRE = regexp.MustCompile(`\\link\{([^{}]*)\}\{([^{}]*)\}`)
+
+// extractLinks replaces each \link{target}{text} in the block's text with the
+// text between LinkStart and LinkEnd, and adds the links to the block.
+func (b *Block) extractLinks() error {
+	matches := linkRE.FindAllStringSubmatchIndex(b.Text, -1)
+	if strings.Count(b.Text, `\link`) != len(matches) {
+		return errors.New(`\link must be followed by {target}{text}`)
+	}
+	for _, m := range matches {
+		target := strings.TrimSpace(b.Text[m[2]:m[3]])
+		if b.Markup {
+			target = markupUnescaper.Replace(target)
+		}
+		b.Links = append(b.Links, Link{Target: target})
+	}
+	// Work backwards, so that the offsets of the matches stay right.
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		b.replace(m[0], m[1], LinkStart+b.Text[m[4]:m[5]]+LinkEnd)
+	}
+	return nil
+}
+
+// markupTagRE matches a tag of Pango markup.
+var markupTagRE = regexp.MustCompile(`<[^>]*>`)
+
+// markupUnescaper undoes markupEscaper, and removes the marks of notes and links.
+var markupUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&",
+	NoteMark, "", LinkStart, "", LinkEnd, "")
+
+// headingAnchor returns the anchor made from the text of a heading: its words in
+// lower case, joined by '-'.
+func headingAnchor(b Block) string {
+	text := b.Text
+	if b.Markup {
+		text = markupTagRE.ReplaceAllString(text, "")
+	}
+	text = markupUnescaper.Replace(text)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// anchorHeadings gives every heading without one an anchor made from its text,
+// numbered if need be so that no two are the same.
+func anchorHeadings(blocks []Block) {
+	used := make(map[string]bool)
+	for _, b := range blocks {
+		if b.Kind == HeadingBlock && b.Anchor != "" {
+			used[b.Anchor] = true
+		}
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		if b.Kind != HeadingBlock || b.Anchor != "" {
+			continue
+		}
+		anchor := headingAnchor(*b)
+		if anchor == "" {
+			anchor = "section"
+		}
+		b.Anchor = anchor
+		for n := 2; used[b.Anchor]; n++ {
+			b.Anchor = anchor + "-" + strconv.Itoa(n)
+		}
+		used[b.Anchor] = true
+	}
+}
+
+// linkSchemes are the schemes of the URLs that links may go to.
+var linkSchemes = []string{"http", "https", "mailto"}
+
+// checkLinks checks that every link is to a heading or to an absolute URL with
+// one of linkSchemes.
+func checkLinks(blocks []Block) error {
+	anchors := make(map[string]bool)
+	for _, b := range blocks {
+		if b.Kind == HeadingBlock {
+			anchors[b.Anchor] = true
+		}
+	}
+	for _, b := range blocks {
+		for _, link := range b.Links {
+			if anchor, ok := link.Internal(); ok {
+				if !anchors[anchor] {
+					return errors.New("No heading to link to for " + link.Target)
+				}
+				continue
+			}
+			u, err := url.Parse(link.Target)
+			if err != nil || u.Scheme == "" {
+				return errors.New("Invalid link target " + link.Target)
+			}
+			if !oneOf(strings.ToLower(u.Scheme), linkSchemes) {
+				return errors.New("Links may not go to " + u.Scheme + " URLs")
+			}
+		}
+	}
+	return nil
+}
+
+// linkBlocks extracts the links of all blocks.
+func linkBlocks(blocks []Block) error {
+	for i := range blocks {
+		if err := blocks[i].extractLinks(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "\\heading{1}{Getting Started}\n\nSee \\link{#getting-started}{the start} and\n" +
+		"\\link{ http://example.com/?a=1&b=2 }{an example}.\n\n\\heading{2}{Getting started}\n\n" +
+		"\\heading{3}{More}{more}"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	if b := blocks[0]; b.Kind != HeadingBlock || b.Level != 1 || b.Text != "Getting Started" {
+		t.Errorf("block 0 is %+v", b)
+	}
+	b := blocks[1]
+	if b.Text != "See "+LinkStart+"the start"+LinkEnd+" and "+LinkStart+"an example"+LinkEnd+"." {
+		t.Errorf("block 1 has text %q", b.Text)
+	}
+	links := []Link{Link{"#getting-started"}, Link{"http://example.com/?a=1&b=2"}}
+	if len(b.Links) != len(links) {
+		t.Fatalf("got %d links", len(b.Links))
+	}
+	for i, link := range links {
+		if b.Links[i] != link {
+			t.Errorf("link %d is %v", i, b.Links[i])
+		}
+	}
+	for i, anchor := range []string{"getting-started", "", "getting-started-2", "more"} {
+		if blocks[i].Anchor != anchor {
+			t.Errorf("block %d has anchor %q", i, blocks[i].Anchor)
+		}
+	}
+}
+
+func TestHeadingAnchor(t *testing.T) {
+	type data struct {
+		Text   string
+		Markup bool
+		Anchor string
+	}
+	var testData []data = []data{
+		data{"Hello, World!", false, "hello-world"},
+		data{"<i>A</i> &amp; B" + NoteMark, true, "a-b"},
+		data{"  2.1  Results ", false, "2-1-results"},
+	}
+	for _, d := range testData {
+		if anchor := headingAnchor(Block{Text: d.Text, Markup: d.Markup}); anchor != d.Anchor {
+			t.Errorf("headingAnchor(%q) was %q", d.Text, anchor)
+		}
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	for _, text := range []string{"A \\link{#nowhere}{b}", "A \\link{example.com}{b}", "A \\link{http://x.org}",
+		"A \\link{javascript:alert(1)}{b}", "A \\link{file:///etc/passwd}{b}", "A \\link{data:text/html,x}{b}",
+		"\\heading{7}{Deep}", "\\heading{1}"} {
+		doc := DefaultDocument()
+		doc.Text = text
+		if _, err := doc.Blocks(); err == nil {
+			t.Errorf("no error for %q", text)
+		}
+	}
+}
+
+func TestLinkSchemes(t *testing.T) {
+	for _, target := range []string{"http://x.org", "https://x.org/a?b=c", "HTTPS://x.org", "mailto:a@x.org"} {
+		doc := DefaultDocument()
+		doc.Text = "A \\link{" + target + "}{b}"
+		if _, err := doc.Blocks(); err != nil {
+			t.Errorf("error for a link to %q: %q", target, err.Error())
+		}
+	}
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	doc := DefaultDocument()
+	doc.TextFormat = Markdown
+	doc.Text = "# Intro\n\nBack to \\link{#intro}{the *intro*}."
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if b := blocks[1]; b.Text != "Back to "+LinkStart+"the <i>intro</i>"+LinkEnd+"." || len(b.Links) != 1 {
+		t.Errorf("block 1 is %q, %v", b.Text, b.Links)
+	}
+}
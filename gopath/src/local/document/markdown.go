@@ -0,0 +1,111 @@
+package document
+
+import (
+	"markdown"
+	"strconv"
+	"strings"
+)
+
+// markupEscaper escapes the characters that are special in Pango markup.
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// inlineMarkup returns the Pango markup for Markdown inlines.
+func inlineMarkup(inlines []*markdown.Inline) string {
+	s := ""
+	for _, inline := range inlines {
+		switch inline.Kind {
+		case markdown.TextInline:
+			s += markupEscaper.Replace(inline.Text)
+		case markdown.SoftBreakInline:
+			s += " "
+		case markdown.HardBreakInline:
+			// A line separator breaks the line without starting a new paragraph.
+			s += "\u2028"
+		case markdown.CodeInline:
+			s += "<tt>" + markupEscaper.Replace(inline.Text) + "</tt>"
+		case markdown.EmphasisInline:
+			s += "<i>" + inlineMarkup(inline.Children) + "</i>"
+		case markdown.StrongInline:
+			s += "<b>" + inlineMarkup(inline.Children) + "</b>"
+		}
+	}
+	return s
+}
+
+// markdownCommand returns the name and arguments of a paragraph holding only a command.
+// ok is false if the paragraph is not a command.
+func markdownCommand(mdblock *markdown.Block) (name string, args []string, ok bool) {
+	if len(mdblock.Inlines) != 1 || mdblock.Inlines[0].Kind != markdown.TextInline {
+		return "", nil, false
+	}
+	return parseCommand(mdblock.Inlines[0].Text)
+}
+
+// markdownImage returns the block for a paragraph holding only an \image command.
+// ok is false if the paragraph is not an image.
+func markdownImage(mdblock *markdown.Block) (block Block, ok bool, err error) {
+	name, args, ok := markdownCommand(mdblock)
+	if !ok || name != "image" {
+		return block, false, nil
+	}
+	block, err = imageBlock(args)
+	return block, true, err
+}
+
+// markdownBlocks returns the blocks for Markdown text.
+// Block quotes and list items become paragraphs of greater depth.
+// A paragraph holding only an \align command sets the alignment of those after it.
+func markdownBlocks(text string) ([]Block, error) {
+	var blocks []Block
+	var err error
+	var alignment, lastLine string
+	var walk func(mdblocks []*markdown.Block, depth int, label string)
+	walk = func(mdblocks []*markdown.Block, depth int, label string) {
+		for _, mdblock := range mdblocks {
+			switch mdblock.Kind {
+			case markdown.ParagraphBlock:
+				if name, args, ok := markdownCommand(mdblock); ok && name == "align" {
+					var aerr error
+					if alignment, lastLine, aerr = parseAlignment(args); aerr != nil && err == nil {
+						err = aerr
+					}
+					continue
+				}
+				image, ok, ierr := markdownImage(mdblock)
+				if ierr != nil && err == nil {
+					err = ierr
+				}
+				if ok {
+					blocks = append(blocks, image)
+				} else {
+					blocks = append(blocks, Block{Kind: ParagraphBlock, Text: inlineMarkup(mdblock.Inlines),
+						Markup: true, Depth: depth, Label: label, Alignment: alignment, LastLine: lastLine})
+				}
+			case markdown.HeadingBlock:
+				blocks = append(blocks, Block{Kind: HeadingBlock, Text: inlineMarkup(mdblock.Inlines),
+					Markup: true, Level: mdblock.Level, Alignment: alignment, LastLine: lastLine})
+			case markdown.RuleBlock:
+				blocks = append(blocks, Block{Kind: RuleBlock})
+			case markdown.BlockQuoteBlock:
+				walk(mdblock.Children, depth+1, "")
+			case markdown.ListBlock:
+				for i, item := range mdblock.Children {
+					itemLabel := "•"
+					if mdblock.Ordered {
+						itemLabel = strconv.Itoa(mdblock.Start+i) + "."
+					}
+					if len(item.Children) == 0 || item.Children[0].Kind != markdown.ParagraphBlock {
+						// Give the label a paragraph of its own.
+						blocks = append(blocks, Block{Kind: ParagraphBlock, Markup: true,
+							Depth: depth + 1, Label: itemLabel, Alignment: alignment, LastLine: lastLine})
+						itemLabel = ""
+					}
+					walk(item.Children, depth+1, itemLabel)
+				}
+			}
+			label = ""
+		}
+	}
+	walk(markdown.Parse(text).Children, 0, "")
+	return blocks, err
+}
@@ -3,6 +3,7 @@ package document
 import (
 	"db"
 	"launchpad.net/mgo/bson"
+	"time"
 )
 
 // Getter and setter for Document Length
@@ -21,6 +22,21 @@ func (l *Length) SetBSON(raw bson.Raw) error {
 	return err
 }
 
+// GetBSON uses the defining string.
+func (c Color) GetBSON() (interface{}, error) {
+	return c.String(), nil
+}
+
+// SetBSON uses the defining string.
+func (c *Color) SetBSON(raw bson.Raw) error {
+	var def string
+	err := raw.Unmarshal(&def)
+	if err == nil {
+		*c, err = colorFromDefinition(def)
+	}
+	return err
+}
+
 func (doc Document) ObjectId() db.Id {
 	return doc.Id
 }
@@ -34,9 +50,17 @@ func bogusCheck() {
 	var _ bson.Getter = l
 	var _ bson.Setter = &l
 
+	var c Color
+	var _ bson.Getter = c
+	var _ bson.Setter = &c
+
 	var doc Document
 	var _ db.DBObject = doc
 	var _ db.DBObjectWriter = &doc
+
+	var img Image
+	var _ db.DBObject = img
+	var _ db.DBObjectWriter = &img
 }
 
 type MongoDB struct {
@@ -56,16 +80,30 @@ func (m *MongoDB) Close() {
 }
 
 const docCollection = "documents"
+const imageCollection = "images"
 
 func (m *MongoDB) Count() int {
 	return m.Database.Count(docCollection)
 }
 
+// now returns the current time, to the second, for document timestamps.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
 func (m *MongoDB) Add(doc *Document) error {
+	doc.Created = now()
+	doc.Modified = doc.Created
 	return m.Database.Add(docCollection, doc)
 }
 
+// Update replaces a document, keeping the time it was created, whatever doc has.
 func (m *MongoDB) Update(doc *Document) error {
+	doc.Created = time.Time{}
+	if old, err := m.Fetch(doc.Id); err == nil {
+		doc.Created = old.Created
+	}
+	doc.Modified = now()
 	return m.Database.Update(docCollection, doc)
 }
 
@@ -79,7 +117,20 @@ func (m *MongoDB) Delete(id db.Id) error {
 	return m.Database.Delete(docCollection, id)
 }
 
+func (m *MongoDB) AddImage(img *Image) error {
+	return m.Database.Add(imageCollection, img)
+}
+
+func (m *MongoDB) FetchImage(id db.Id) (Image, error) {
+	var img Image
+	err := m.Database.Fetch(imageCollection, id, &img)
+	return img, err
+}
+
 func (m *MongoDB) DeleteAll() error {
+	if err := m.Database.DeleteAll(imageCollection); err != nil {
+		return err
+	}
 	return m.Database.DeleteAll(docCollection)
 }
 
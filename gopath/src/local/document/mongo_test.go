@@ -3,6 +3,7 @@ package document
 import (
 	"db"
 	"testing"
+	"time"
 )
 
 func TestMongoDB(t *testing.T) {
@@ -53,6 +54,8 @@ func TestMongoDB(t *testing.T) {
 		}
 		testText := "Friends! Romans! Countrymen!"
 		doc.Text = testText
+		created := doc.Created
+		doc.Created = time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
 		err = mdb.Update(&doc)
 		if err != nil {
 			t.Errorf("Could not update document")
@@ -64,6 +67,9 @@ func TestMongoDB(t *testing.T) {
 		if doc2.Text != testText {
 			t.Errorf("Text was not updated (%q)", doc2.Text)
 		}
+		if !doc2.Created.Equal(created) {
+			t.Errorf("Creation time was changed to %v", doc2.Created)
+		}
 	}
 	{
 		newId, _ := db.NewId()
@@ -93,3 +99,30 @@ func TestMongoDB(t *testing.T) {
 	}
 
 }
+
+func TestMongoImages(t *testing.T) {
+	mdb, err := CreateMongoDB("localhost", "testdb")
+	if err != nil {
+		t.Fatalf("Could not create DB: %q", err.Error())
+	}
+	defer mdb.Close()
+	defer mdb.DeleteAll()
+
+	img, err := NewImage(encodeImage("image/png", 3, 2))
+	if err != nil {
+		t.Fatalf("NewImage failed: %q", err.Error())
+	}
+	if err := mdb.AddImage(img); err != nil {
+		t.Fatalf("Could not add image: %q", err.Error())
+	}
+	img2, err := mdb.FetchImage(img.Id)
+	if err != nil {
+		t.Fatalf("Could not find image")
+	}
+	if img2.ContentType != img.ContentType || string(img2.Data) != string(img.Data) {
+		t.Errorf("Fetched image was %q, %q", img2.ContentType, img2.Data)
+	}
+	if img2.Width != 3 || img2.Height != 2 {
+		t.Errorf("Fetched image was %dx%d", img2.Width, img2.Height)
+	}
+}
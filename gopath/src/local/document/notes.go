@@ -0,0 +1,125 @@
+package document
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NoteMark marks the place of a note's reference in the text of a block.
+const NoteMark = "\ufffc"
+
+// Note is a footnote of a block. Its Text has the same format as the block's.
+type Note struct {
+	// Number is the number of the note, counting from 1 through the document.
+	Number int
+	Text   string
+}
+
+// footnoteRE matches a footnote, \footnote{text}.
+var footnoteRE = regexp.MustCompile(`\\footnote\{([^{}]*)\}`)
+
+// sourceLines sorts source lines by offset.
+type sourceLines []sourceLine
+
+func (s sourceLines) Len() int           { return len(s) }
+func (s sourceLines) Less(i, j int) bool { return s[i].offset < s[j].offset }
+func (s sourceLines) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// replace replaces the bytes of the block's text from start to end with s,
+// keeping the positions of the text after them.
+func (b *Block) replace(start, end int, s string) {
+	line, column := b.Position(end)
+	shift := len(s) - (end - start)
+	var source []sourceLine
+	for _, src := range b.source {
+		switch {
+		case src.offset <= start:
+			source = append(source, src)
+		case src.offset >= end:
+			src.offset += shift
+			source = append(source, src)
+		}
+	}
+	if line > 0 {
+		source = append(source, sourceLine{offset: start + len(s), line: line, column: column})
+		sort.Sort(sourceLines(source))
+	}
+	b.Text = b.Text[:start] + s + b.Text[end:]
+	b.source = source
+}
+
+// extractNotes replaces each \footnote{text} in the block's text with NoteMark,
+// and adds the notes to the block, numbered from number.
+// It returns the number of the next note.
+func (b *Block) extractNotes(number int) (int, error) {
+	matches := footnoteRE.FindAllStringSubmatchIndex(b.Text, -1)
+	if strings.Count(b.Text, `\footnote`) != len(matches) {
+		return number, errors.New(`\footnote must be followed by {text}`)
+	}
+	for _, m := range matches {
+		b.Notes = append(b.Notes, Note{Number: number, Text: strings.TrimSpace(b.Text[m[2]:m[3]])})
+		number++
+	}
+	// Work backwards, so that the offsets of the matches stay right.
+	for i := len(matches) - 1; i >= 0; i-- {
+		b.replace(matches[i][0], matches[i][1], NoteMark)
+	}
+	return number, nil
+}
+
+// numberNotes extracts and numbers the notes of all blocks.
+func numberNotes(blocks []Block) error {
+	number := 1
+	for i := range blocks {
+		var err error
+		if number, err = blocks[i].extractNotes(number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultEndnotesTitle is the heading of endnotes in documents without one.
+const DefaultEndnotesTitle = "Notes"
+
+// endnotes moves the notes of the blocks to a list at the end, under a heading
+// of title. The reference to each note becomes its number as a superscript.
+func endnotes(blocks []Block, title string) []Block {
+	var notes []Block
+	for i := range blocks {
+		b := &blocks[i]
+		if len(b.Notes) == 0 {
+			continue
+		}
+		plain := !b.Markup
+		if plain {
+			b.Text = markupEscaper.Replace(b.Text)
+			b.source = nil
+			b.Markup = true
+		}
+		for j := len(b.Notes) - 1; j >= 0; j-- {
+			k := strings.LastIndex(b.Text, NoteMark)
+			if k < 0 {
+				break
+			}
+			b.replace(k, k+len(NoteMark), "<sup>"+strconv.Itoa(b.Notes[j].Number)+"</sup>")
+		}
+		for _, note := range b.Notes {
+			text := note.Text
+			if plain {
+				text = markupEscaper.Replace(text)
+			}
+			notes = append(notes, Block{Kind: ParagraphBlock, Text: text, Markup: true,
+				Depth: 1, Label: strconv.Itoa(note.Number) + "."})
+		}
+		b.Notes = nil
+	}
+	if len(notes) == 0 {
+		return blocks
+	}
+	blocks = append(blocks, Block{Kind: HeadingBlock, Text: title, Level: 2})
+	return append(blocks, notes...)
+}
@@ -0,0 +1,109 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestFootnotes(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "One\\footnote{First.} two.\n\nThree\\footnote{ Second. }\nfour\\footnote{Third.} <b"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	if blocks[0].Text != "One"+NoteMark+" two." {
+		t.Errorf("block 0 has text %q", blocks[0].Text)
+	}
+	if blocks[1].Text != "Three"+NoteMark+" four"+NoteMark+" <b" {
+		t.Errorf("block 1 has text %q", blocks[1].Text)
+	}
+	notes := []Note{Note{1, "First."}, Note{2, "Second."}, Note{3, "Third."}}
+	got := append(blocks[0].Notes, blocks[1].Notes...)
+	if len(got) != len(notes) {
+		t.Fatalf("got %d notes", len(got))
+	}
+	for i, note := range notes {
+		if got[i] != note {
+			t.Errorf("note %d is %v", i, got[i])
+		}
+	}
+	// The positions of the text after a note are kept.
+	b := blocks[1]
+	if line, column := b.Position(len(b.Text) - 2); line != 4 || column != 23 {
+		t.Errorf("Position of <b is %d, %d", line, column)
+	}
+}
+
+func TestEndnotes(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "A & B\\footnote{C & D}."
+	doc.Endnotes = true
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	if b := blocks[0]; b.Text != "A &amp; B<sup>1</sup>." || !b.Markup || len(b.Notes) != 0 {
+		t.Errorf("block 0 is %q, %v, %v", b.Text, b.Markup, b.Notes)
+	}
+	if b := blocks[1]; b.Kind != HeadingBlock || b.Text != "Notes" {
+		t.Errorf("block 1 is %q, of kind %d", b.Text, b.Kind)
+	}
+	if b := blocks[2]; b.Text != "C &amp; D" || b.Label != "1." || !b.Markup {
+		t.Errorf("block 2 is %q, %q, %v", b.Text, b.Label, b.Markup)
+	}
+}
+
+func TestEndnotesTitle(t *testing.T) {
+	type data struct {
+		Title   string
+		Heading string
+	}
+	var testData []data = []data{data{"Anmerkungen", "Anmerkungen"},
+		data{"", DefaultEndnotesTitle},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.Text = "A\\footnote{B}."
+		doc.Endnotes = true
+		doc.EndnotesTitle = d.Title
+		blocks, err := doc.Blocks()
+		if err != nil || len(blocks) != 3 {
+			t.Fatalf("Blocks returned %d blocks, %v", len(blocks), err)
+		}
+		if b := blocks[1]; b.Text != d.Heading {
+			t.Errorf("endnotes titled %q have the heading %q", d.Title, b.Text)
+		}
+	}
+}
+
+func TestFootnoteErrors(t *testing.T) {
+	for _, text := range []string{"A \\footnote{unclosed", "A \\footnote B", "\\footnote{a {b} c}"} {
+		doc := DefaultDocument()
+		doc.Text = text
+		if _, err := doc.Blocks(); err == nil {
+			t.Errorf("no error for %q", text)
+		}
+	}
+}
+
+func TestMarkdownFootnotes(t *testing.T) {
+	doc := DefaultDocument()
+	doc.TextFormat = Markdown
+	doc.Text = "Some *text*\\footnote{A *note*}."
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if len(blocks) != 1 || blocks[0].Text != "Some <i>text</i>"+NoteMark+"." {
+		t.Fatalf("got blocks %v", blocks)
+	}
+	if notes := blocks[0].Notes; len(notes) != 1 || notes[0].Text != "A <i>note</i>" {
+		t.Errorf("got notes %v", notes)
+	}
+}
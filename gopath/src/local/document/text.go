@@ -0,0 +1,307 @@
+package document
+
+import (
+	"db"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BlockKind is the "enum" type for the kinds of blocks in a document's text.
+type BlockKind int
+
+// an enumeration for block kinds.
+const (
+	_ BlockKind = iota
+	ParagraphBlock
+	PageBreakBlock
+	HeadingBlock
+	RuleBlock
+	ImageBlock
+)
+
+// Block is a piece of a document's text, as split up by Blocks.
+type Block struct {
+	Kind BlockKind
+	// Text is the text of a ParagraphBlock or HeadingBlock.
+	Text string
+	// Markup is true if Text is Pango markup.
+	Markup bool
+	// Level is the level of a HeadingBlock, starting at 1.
+	Level int
+	// Anchor is the name of a HeadingBlock, for links to it.
+	Anchor string
+	// Depth is the nesting depth of a paragraph inside block quotes and lists.
+	Depth int
+	// Label is the bullet or number of a paragraph starting a list item.
+	Label string
+	// Alignment and LastLine override the alignment and last line alignment of the
+	// document for a paragraph or heading, if they are not empty.
+	Alignment string
+	LastLine  string
+	// PageWidth and PageHeight are the size of the pages following a PageBreakBlock.
+	// They are zero if the page size does not change.
+	PageWidth  Length
+	PageHeight Length
+	// Image is the Id of the image of an ImageBlock, to be set at the size
+	// ImageWidth by ImageHeight. If either is zero, it follows from the other
+	// and the image's shape, and if both are, from the image's size in pixels.
+	Image       db.Id
+	ImageWidth  Length
+	ImageHeight Length
+	// Notes are the footnotes of a paragraph or heading, whose references are
+	// marked in Text with NoteMark.
+	Notes []Note
+	// Links are the targets of the links of a paragraph or heading, whose texts
+	// are marked in Text by LinkStart and LinkEnd.
+	Links []Link
+	// source records where the lines of Text came from.
+	source []sourceLine
+}
+
+// sourceLine records the position of a line of a block's text in the document text.
+type sourceLine struct {
+	// offset is the byte offset of the line in the block's text.
+	offset int
+	// line and column are the position of the line's start in the document text,
+	// starting at 1. The column counts characters.
+	line   int
+	column int
+}
+
+// Position returns the line and column, starting at 1, in the document text
+// of a byte offset into the block's text.
+// It returns 0, 0 if the position is unknown.
+func (b Block) Position(offset int) (line, column int) {
+	for i := len(b.source) - 1; i >= 0; i-- {
+		src := b.source[i]
+		if offset >= src.offset && offset <= len(b.Text) {
+			return src.line, src.column + utf8.RuneCountInString(b.Text[src.offset:offset])
+		}
+	}
+	return 0, 0
+}
+
+// commandRE matches a line holding a command, such as \par or \pagesize{11in}{8.5in}.
+var commandRE = regexp.MustCompile(`^\s*\\([a-z]+)((?:\{[^{}]*\})*)\s*$`)
+
+// argRE matches a single command argument.
+var argRE = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// parseCommand returns the name and arguments of a command line.
+// ok is false if the line is not a command.
+func parseCommand(line string) (name string, args []string, ok bool) {
+	m := commandRE.FindStringSubmatch(line)
+	if m == nil {
+		return "", nil, false
+	}
+	for _, arg := range argRE.FindAllStringSubmatch(m[2], -1) {
+		args = append(args, arg[1])
+	}
+	return m[1], args, true
+}
+
+// pageBreak returns the block for a \newpage or \pagesize command.
+func pageBreak(name string, args []string) (Block, error) {
+	block := Block{Kind: PageBreakBlock}
+	switch name {
+	case "newpage":
+		if len(args) != 0 {
+			return block, errors.New(`\newpage takes no arguments`)
+		}
+	case "pagesize":
+		if len(args) != 2 {
+			return block, errors.New(`\pagesize takes a width and a height`)
+		}
+		var err error
+		if block.PageWidth, err = LengthFromString(args[0]); err != nil {
+			return block, errors.New("Invalid page width " + args[0])
+		}
+		if block.PageHeight, err = LengthFromString(args[1]); err != nil {
+			return block, errors.New("Invalid page height " + args[1])
+		}
+		if block.PageWidth.Points() <= 0 || block.PageHeight.Points() <= 0 {
+			return block, errors.New("Page sizes must be positive")
+		}
+	default:
+		return block, errors.New(`Unknown command \` + name)
+	}
+	return block, nil
+}
+
+// imageBlock returns the block for an \image{id}{width}{height} command.
+// The width and height may be left out or empty.
+func imageBlock(args []string) (Block, error) {
+	block := Block{Kind: ImageBlock}
+	if len(args) < 1 || len(args) > 3 || strings.TrimSpace(args[0]) == "" {
+		return block, errors.New(`\image takes an image id, and optionally a width and a height`)
+	}
+	block.Image = db.MakeId(strings.TrimSpace(args[0]))
+	sizes := []*Length{&block.ImageWidth, &block.ImageHeight}
+	for i, arg := range args[1:] {
+		if strings.TrimSpace(arg) == "" {
+			continue
+		}
+		size, err := LengthFromString(arg)
+		if err != nil || size.Points() <= 0 {
+			return block, errors.New("Invalid image size " + arg)
+		}
+		*sizes[i] = size
+	}
+	return block, nil
+}
+
+// maxHeadingLevel is the deepest level of a heading.
+const maxHeadingLevel = 6
+
+// headingBlock returns the block for a \heading{level}{text}{anchor} command.
+// The anchor may be left out.
+func headingBlock(args []string, markup bool) (Block, error) {
+	block := Block{Kind: HeadingBlock, Markup: markup}
+	if len(args) < 2 || len(args) > 3 {
+		return block, errors.New(`\heading takes a level, a text and optionally an anchor`)
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(args[0]))
+	if err != nil || level < 1 || level > maxHeadingLevel {
+		return block, errors.New("Invalid heading level " + args[0])
+	}
+	block.Level = level
+	block.Text = strings.TrimSpace(args[1])
+	if len(args) == 3 {
+		block.Anchor = strings.TrimSpace(args[2])
+	}
+	return block, nil
+}
+
+// Blocks splits the document text into blocks.
+// It fails if the text has a bad command or the document has an unknown TextFormat.
+// Markdown text is parsed as Markdown; otherwise the text is split as follows.
+//
+// Paragraphs are separated by blank lines or by a line consisting of \par.
+// The lines of a paragraph are joined with spaces.
+// A line consisting of \newpage starts a new page, and one consisting of
+// \pagesize{width}{height} starts a new page of the given size; the size
+// holds for following pages until it is changed again.
+// A line consisting of \image{id}{width}{height} sets an uploaded image, and
+// is also recognized in Markdown.
+//
+// A line consisting of \heading{level}{text} is a heading, of level 1 to 6, and
+// \heading{level}{text}{anchor} also names it for links.
+//
+// A line consisting of \align{alignment} or \align{alignment}{last line} sets the
+// alignment of the paragraphs and headings after it, until the next \align, and
+// \align{} goes back to the document's. It is also recognized in Markdown.
+//
+// In all formats, \footnote{text} makes a footnote, numbered through the document.
+// If the document has Endnotes, the notes are instead listed at the end.
+// \link{target}{text} links text to an http, https or mailto URL, or to the heading
+// with the anchor after a '#' in target. Headings without an anchor are given one
+// made from their text.
+func (doc *Document) Blocks() ([]Block, error) {
+	var blocks []Block
+	var err error
+	switch doc.TextFormat {
+	case "", PlainText, PangoMarkup:
+		blocks, err = doc.textBlocks()
+	case Markdown:
+		blocks, err = markdownBlocks(doc.Text)
+	default:
+		return nil, errors.New("Unknown text format " + doc.TextFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := linkBlocks(blocks); err != nil {
+		return nil, err
+	}
+	if err := numberNotes(blocks); err != nil {
+		return nil, err
+	}
+	if doc.Endnotes {
+		title := doc.EndnotesTitle
+		if title == "" {
+			title = DefaultEndnotesTitle
+		}
+		blocks = endnotes(blocks, title)
+	}
+	anchorHeadings(blocks)
+	if err := checkLinks(blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// textBlocks splits plain text or Pango markup into blocks.
+func (doc *Document) textBlocks() ([]Block, error) {
+	markup := doc.TextFormat == PangoMarkup
+	var blocks []Block
+	var lines []string
+	var source []sourceLine
+	var alignment, lastLine string
+	offset := 0
+	flush := func() {
+		if len(lines) > 0 {
+			text := strings.Join(lines, " ")
+			blocks = append(blocks, Block{Kind: ParagraphBlock, Text: text, Markup: markup,
+				Alignment: alignment, LastLine: lastLine, source: source})
+			lines = nil
+			source = nil
+			offset = 0
+		}
+	}
+	for i, line := range strings.Split(doc.Text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if name, args, ok := parseCommand(trimmed); ok && name != "footnote" && name != "link" {
+			flush()
+			if name == "par" {
+				if len(args) != 0 {
+					return nil, errors.New(`\par takes no arguments`)
+				}
+				continue
+			}
+			if name == "align" {
+				var err error
+				if alignment, lastLine, err = parseAlignment(args); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if name == "heading" {
+				block, err := headingBlock(args, markup)
+				if err != nil {
+					return nil, err
+				}
+				block.Alignment, block.LastLine = alignment, lastLine
+				blocks = append(blocks, block)
+				continue
+			}
+			if name == "image" {
+				block, err := imageBlock(args)
+				if err != nil {
+					return nil, err
+				}
+				blocks = append(blocks, block)
+				continue
+			}
+			block, err := pageBreak(name, args)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+		indent := strings.Index(line, trimmed)
+		column := utf8.RuneCountInString(line[:indent]) + 1
+		source = append(source, sourceLine{offset: offset, line: i + 1, column: column})
+		offset += len(trimmed) + 1
+		lines = append(lines, trimmed)
+	}
+	flush()
+	return blocks, nil
+}
@@ -0,0 +1,192 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "Portrait\n\\pagesize{11in}{8.5in}\nLandscape\nstill\n\\newpage\nMore"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	kinds := []BlockKind{ParagraphBlock, PageBreakBlock, ParagraphBlock, PageBreakBlock, ParagraphBlock}
+	if len(blocks) != len(kinds) {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	for i, kind := range kinds {
+		if blocks[i].Kind != kind {
+			t.Errorf("block %d has kind %d", i, blocks[i].Kind)
+		}
+	}
+	if blocks[2].Text != "Landscape still" {
+		t.Errorf("block 2 has text %q", blocks[2].Text)
+	}
+	if w, h := blocks[1].PageWidth.Points(), blocks[1].PageHeight.Points(); w != 792 || h != 612 {
+		t.Errorf("page size was %g x %g", w, h)
+	}
+	if w := blocks[3].PageWidth.Points(); w != 0 {
+		t.Errorf("newpage changed the page width to %g", w)
+	}
+}
+
+func TestParagraphs(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "First\nparagraph.\n\n  \nSecond.\n\\par\nThird.\n"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	texts := []string{"First paragraph.", "Second.", "Third."}
+	if len(blocks) != len(texts) {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	for i, text := range texts {
+		if blocks[i].Kind != ParagraphBlock || blocks[i].Text != text {
+			t.Errorf("block %d is %d, %q", i, blocks[i].Kind, blocks[i].Text)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	doc := DefaultDocument()
+	doc.Text = "Title\n\n  Жирный\n  <b>bold\n"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	text := blocks[1].Text
+	offset := strings.Index(text, "<b>")
+	if line, column := blocks[1].Position(offset); line != 4 || column != 3 {
+		t.Errorf("<b> is at %d:%d", line, column)
+	}
+	offset = strings.Index(text, "ный")
+	if line, column := blocks[1].Position(offset); line != 3 || column != 6 {
+		t.Errorf("ный is at %d:%d", line, column)
+	}
+	if line, column := blocks[1].Position(len(text) + 1); line != 0 || column != 0 {
+		t.Errorf("position past the end is %d:%d", line, column)
+	}
+}
+
+func TestBlockErrors(t *testing.T) {
+	bad := []string{`\pagesize{11in}`, `\pagesize{11}{8.5in}`, `\pagesize{0in}{8.5in}`,
+		`\newpage{1in}`, `\par{}`, `\frobnicate`, `\image`, `\image{}`, `\image{logo}{wide}`,
+		`\image{logo}{1in}{2in}{3in}`, `\align{ragged}`, `\align{justify}{end}`, `\align`}
+	for _, text := range bad {
+		doc := DefaultDocument()
+		doc.Text = text
+		if _, err := doc.Blocks(); err == nil {
+			t.Errorf("no error for %q", text)
+		}
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	doc := DefaultDocument()
+	for _, format := range []string{"", PlainText, PangoMarkup} {
+		doc.TextFormat = format
+		if _, err := doc.Blocks(); err != nil {
+			t.Errorf("format %q returned error %q", format, err.Error())
+		}
+	}
+	doc.TextFormat = "troff"
+	if _, err := doc.Blocks(); err == nil {
+		t.Errorf("no error for format %q", doc.TextFormat)
+	}
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	doc := DefaultDocument()
+	doc.TextFormat = Markdown
+	doc.Text = "# A & B\n\nSome *text*  \nhere.\n\n1. one\n\n   more\n2. two\n\n> quoted\n\n---"
+	blocks, err := doc.Blocks()
+	if err != nil {
+		t.Fatalf("Blocks returned error %q", err.Error())
+	}
+	expected := []Block{Block{Kind: HeadingBlock, Text: "A &amp; B", Markup: true, Level: 1},
+		Block{Kind: ParagraphBlock, Text: "Some <i>text</i>\u2028here.", Markup: true},
+		Block{Kind: ParagraphBlock, Text: "one", Markup: true, Depth: 1, Label: "1."},
+		Block{Kind: ParagraphBlock, Text: "more", Markup: true, Depth: 1},
+		Block{Kind: ParagraphBlock, Text: "two", Markup: true, Depth: 1, Label: "2."},
+		Block{Kind: ParagraphBlock, Text: "quoted", Markup: true, Depth: 1},
+		Block{Kind: RuleBlock}}
+	if len(blocks) != len(expected) {
+		t.Fatalf("got %d blocks", len(blocks))
+	}
+	for i, block := range expected {
+		b := blocks[i]
+		if b.Kind != block.Kind || b.Text != block.Text || b.Markup != block.Markup ||
+			b.Level != block.Level || b.Depth != block.Depth || b.Label != block.Label {
+			t.Errorf("block %d is %+v", i, b)
+		}
+	}
+}
+
+func TestImageBlocks(t *testing.T) {
+	type data struct {
+		Format        string
+		Text          string
+		Id            string
+		Width, Height float64
+	}
+	var testData []data = []data{data{PlainText, `\image{logo}`, "logo", 0, 0},
+		data{PangoMarkup, ` \image{ logo }{2in} `, "logo", 144, 0},
+		data{PlainText, `\image{figure-1}{}{1in}`, "figure-1", 0, 72},
+		data{Markdown, "# Title\n\n\\image{logo}{1in}{2in}\n", "logo", 72, 144},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.TextFormat = d.Format
+		doc.Text = d.Text
+		blocks, err := doc.Blocks()
+		if err != nil {
+			t.Errorf("%q returned error %q", d.Text, err.Error())
+			continue
+		}
+		b := blocks[len(blocks)-1]
+		if b.Kind != ImageBlock || b.Image.String() != d.Id {
+			t.Errorf("%q gave block %d for image %q", d.Text, b.Kind, b.Image)
+		}
+		if w, h := b.ImageWidth.Points(), b.ImageHeight.Points(); w != d.Width || h != d.Height {
+			t.Errorf("%q gave size %g x %g", d.Text, w, h)
+		}
+	}
+}
+
+func TestAlignBlocks(t *testing.T) {
+	type data struct {
+		Format     string
+		Text       string
+		Alignments []string
+	}
+	var testData []data = []data{data{PlainText, "One\n\n\\align{center}\nTwo\n\nThree\n\\align{}\nFour",
+		[]string{"", "", "center", "", "center", "", "", ""}},
+		data{PangoMarkup, "\\align{justify}{right}\n\\heading{1}{Title}\nText",
+			[]string{"justify", "right", "justify", "right"}},
+		data{Markdown, "\\align{left}\n\n# Poem\n\nLine one  \nline two\n\n\\align{justify-all-lines}{}\n\nProse.",
+			[]string{"left", "", "left", "", "justify-all-lines", ""}},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.TextFormat = d.Format
+		doc.Text = d.Text
+		blocks, err := doc.Blocks()
+		if err != nil {
+			t.Errorf("%q returned error %q", d.Text, err.Error())
+			continue
+		}
+		var alignments []string
+		for _, b := range blocks {
+			alignments = append(alignments, b.Alignment, b.LastLine)
+		}
+		if strings.Join(alignments, ",") != strings.Join(d.Alignments, ",") {
+			t.Errorf("%q gave alignments %q", d.Text, alignments)
+		}
+	}
+}
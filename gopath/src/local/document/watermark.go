@@ -0,0 +1,115 @@
+package document
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Watermark placements, which say where the middle of a watermark is: in the middle
+// of the page, or of its top or bottom margin.
+const (
+	WatermarkCenter = "center"
+	WatermarkTop    = "top"
+	WatermarkBottom = "bottom"
+)
+
+// WatermarkPlacements are the placements of watermarks.
+var WatermarkPlacements = []string{WatermarkCenter, WatermarkTop, WatermarkBottom}
+
+// Watermark is text stamped on pages, such as "DRAFT" or "CONFIDENTIAL".
+type Watermark struct {
+	Text string
+	// Font is the font family of the text, or empty for the document's font, and
+	// FontSize its size, or DefaultWatermarkSize if zero.
+	Font     string
+	FontSize Length
+	// Color is the color of the text, black if unset, and Opacity how opaque it is,
+	// from more than 0 to 1.
+	Color   Color
+	Opacity float64
+	// Angle is how far the text is turned counterclockwise, in degrees.
+	Angle float64
+	// Placement is one of WatermarkPlacements. An empty placement is WatermarkCenter.
+	Placement string
+	// Pages are the pages to stamp, as parsed by ParsePageRanges. Empty Pages are all pages.
+	Pages string
+	// Over sets the watermark over the text of the pages, rather than under it.
+	Over bool
+}
+
+// DefaultWatermarkSize is the font size of a watermark without one.
+const DefaultWatermarkSize = 72
+
+// DefaultWatermark returns a watermark with the given text, set large and pale
+// across the middle of every page, under the text.
+func DefaultWatermark(text string) Watermark {
+	color, _ := ColorFromString("gray")
+	return Watermark{
+		Text:      text,
+		FontSize:  LengthFromPoints(DefaultWatermarkSize),
+		Color:     color,
+		Opacity:   0.3,
+		Angle:     45,
+		Placement: WatermarkCenter,
+	}
+}
+
+// PageRange is a range of pages, from First to Last, counting from 1.
+// A Last of 0 goes to the last page.
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// Contains returns true if a page is in the range.
+func (r PageRange) Contains(page int) bool {
+	return page >= r.First && (r.Last == 0 || page <= r.Last)
+}
+
+// ParsePageRanges returns the page ranges of a comma-separated list of pages, such
+// as "1-3, 5, 8-", where "8-" is the eighth page to the last. An empty list has no ranges.
+func ParsePageRanges(s string) ([]PageRange, error) {
+	var ranges []PageRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			first, last = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		var r PageRange
+		var err error
+		if r.First, err = strconv.Atoi(first); err != nil || r.First < 1 {
+			return nil, errors.New("Invalid page range " + part)
+		}
+		if last != "" {
+			if r.Last, err = strconv.Atoi(last); err != nil || r.Last < r.First {
+				return nil, errors.New("Invalid page range " + part)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// validateWatermarks checks the watermarks of a document.
+func (doc *Document) validateWatermarks() error {
+	for _, w := range doc.Watermarks {
+		if strings.TrimSpace(w.Text) == "" {
+			return errors.New("Watermarks must have text")
+		}
+		if w.Opacity <= 0 || w.Opacity > 1 {
+			return errors.New("Watermark opacity must be more than 0 and at most 1")
+		}
+		if !oneOf(w.Placement, WatermarkPlacements) {
+			return errors.New("Unknown watermark placement " + w.Placement)
+		}
+		if _, err := ParsePageRanges(w.Pages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
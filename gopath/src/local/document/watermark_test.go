@@ -0,0 +1,95 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	type data struct {
+		Pages  string
+		Ranges []PageRange
+		Ok     bool
+	}
+	var testData []data = []data{data{"", nil, true},
+		data{"1-3, 5, 8-", []PageRange{PageRange{1, 3}, PageRange{5, 5}, PageRange{8, 0}}, true},
+		data{" 2 - 4 ,", []PageRange{PageRange{2, 4}}, true},
+		data{"0", nil, false},
+		data{"-3", nil, false},
+		data{"4-2", nil, false},
+		data{"one", nil, false},
+	}
+	for _, d := range testData {
+		ranges, err := ParsePageRanges(d.Pages)
+		if (err == nil) != d.Ok || len(ranges) != len(d.Ranges) {
+			t.Errorf("ParsePageRanges(%q) returned %v, %v", d.Pages, ranges, err)
+			continue
+		}
+		for i, r := range ranges {
+			if r != d.Ranges[i] {
+				t.Errorf("ParsePageRanges(%q) returned %v", d.Pages, ranges)
+				break
+			}
+		}
+	}
+}
+
+func TestPageRangeContains(t *testing.T) {
+	type data struct {
+		Range PageRange
+		Page  int
+		In    bool
+	}
+	var testData []data = []data{data{PageRange{1, 3}, 1, true},
+		data{PageRange{1, 3}, 3, true},
+		data{PageRange{1, 3}, 4, false},
+		data{PageRange{5, 5}, 5, true},
+		data{PageRange{5, 5}, 4, false},
+		data{PageRange{8, 0}, 100, true},
+		data{PageRange{8, 0}, 7, false},
+	}
+	for _, d := range testData {
+		if in := d.Range.Contains(d.Page); in != d.In {
+			t.Errorf("%v.Contains(%d) was %v", d.Range, d.Page, in)
+		}
+	}
+}
+
+func TestValidateWatermarks(t *testing.T) {
+	draft := DefaultWatermark("DRAFT")
+	noText := DefaultWatermark(" ")
+	clear := DefaultWatermark("CONFIDENTIAL")
+	clear.Opacity = 0
+	misplaced := DefaultWatermark("CONFIDENTIAL")
+	misplaced.Placement = "left"
+	badPages := DefaultWatermark("DRAFT")
+	badPages.Pages = "3-1"
+	type data struct {
+		Watermark Watermark
+		Ok        bool
+	}
+	var testData []data = []data{data{draft, true},
+		data{noText, false},
+		data{clear, false},
+		data{misplaced, false},
+		data{badPages, false},
+	}
+	for _, d := range testData {
+		doc := DefaultDocument()
+		doc.Watermarks = []Watermark{d.Watermark}
+		if err := doc.Validate(); (err == nil) != d.Ok {
+			t.Errorf("Validate() with %+v returned %v", d.Watermark, err)
+		}
+	}
+}
+
+func TestWatermarkJSON(t *testing.T) {
+	var w Watermark
+	data := `{"Text": "DRAFT", "Color": "red", "Opacity": 0.5, "Angle": 30, "Pages": "2-"}`
+	if err := json.Unmarshal([]byte(data), &w); err != nil {
+		t.Fatalf("could not unmarshal a watermark: %v", err)
+	}
+	if w.Text != "DRAFT" || w.Color.String() != "red" || w.FontSize.Points() != 0 || w.Opacity != 0.5 {
+		t.Errorf("unmarshaled watermark %+v", w)
+	}
+}
@@ -0,0 +1,325 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// InlineKind is the "enum" type for kinds of inlines.
+type InlineKind int
+
+// an enumeration for inline kinds.
+const (
+	_ InlineKind = iota
+	TextInline
+	SoftBreakInline
+	HardBreakInline
+	CodeInline
+	EmphasisInline
+	StrongInline
+)
+
+// Inline is a node in the inline content of a paragraph or heading.
+type Inline struct {
+	Kind InlineKind
+	// Text is the text of a text or code inline.
+	Text string
+	// Children is the content of emphasis and strong emphasis.
+	Children []*Inline
+}
+
+// delimiterRun is a run of * or _ characters, which may open or close emphasis.
+type delimiterRun struct {
+	char     byte
+	count    int
+	original int
+	canOpen  bool
+	canClose bool
+}
+
+// inlineItem is an element of the doubly-linked list used while resolving emphasis.
+// It holds either an inline or a delimiter run.
+type inlineItem struct {
+	inline     *Inline
+	run        *delimiterRun
+	prev, next *inlineItem
+	// position is the number of items pushed before this one.
+	position int
+}
+
+// inlineList is a doubly-linked list of inline items.
+type inlineList struct {
+	head, tail *inlineItem
+	pushed     int
+}
+
+func (l *inlineList) push(item *inlineItem) {
+	item.position = l.pushed
+	l.pushed++
+	item.prev = l.tail
+	if l.tail == nil {
+		l.head = item
+	} else {
+		l.tail.next = item
+	}
+	l.tail = item
+}
+
+// pushText adds text, merging it with the last item if that is also text.
+func (l *inlineList) pushText(text string) {
+	if text == "" {
+		return
+	}
+	if l.tail != nil && l.tail.inline != nil && l.tail.inline.Kind == TextInline {
+		l.tail.inline.Text += text
+		return
+	}
+	l.push(&inlineItem{inline: &Inline{Kind: TextInline, Text: text}})
+}
+
+// isPunctuation returns true for ASCII and Unicode punctuation, in the CommonMark sense.
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// isEscapable returns true for the characters that a backslash escapes.
+func isEscapable(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// newDelimiterRun returns the delimiter run text[start:end], with its
+// opening and closing abilities set from the flanking characters.
+func newDelimiterRun(text string, start, end int) *delimiterRun {
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	if end < len(text) {
+		after, _ = utf8.DecodeRuneInString(text[end:])
+	}
+	leftFlanking := !unicode.IsSpace(after) &&
+		(!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+	rightFlanking := !unicode.IsSpace(before) &&
+		(!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+	run := &delimiterRun{char: text[start], count: end - start, original: end - start}
+	if run.char == '*' {
+		run.canOpen = leftFlanking
+		run.canClose = rightFlanking
+	} else {
+		run.canOpen = leftFlanking && (!rightFlanking || isPunctuation(before))
+		run.canClose = rightFlanking && (!leftFlanking || isPunctuation(after))
+	}
+	return run
+}
+
+// parseInlines parses the inline content of a paragraph or heading.
+func parseInlines(text string) []*Inline {
+	var list inlineList
+	var buf []byte
+	flush := func() {
+		list.pushText(string(buf))
+		buf = buf[:0]
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			flush()
+			list.push(&inlineItem{inline: &Inline{Kind: HardBreakInline}})
+			i += 2
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '\\' && i+1 < len(text) && isEscapable(text[i+1]):
+			buf = append(buf, text[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			// Two or more trailing spaces make a hard break.
+			trimmed := strings.TrimRight(string(buf), " ")
+			hard := len(buf)-len(trimmed) >= 2
+			buf = append(buf[:0], trimmed...)
+			flush()
+			kind := SoftBreakInline
+			if hard {
+				kind = HardBreakInline
+			}
+			list.push(&inlineItem{inline: &Inline{Kind: kind}})
+			i++
+			// Leading spaces on the next line are ignored.
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '`':
+			end := i
+			for end < len(text) && text[end] == '`' {
+				end++
+			}
+			ticks := text[i:end]
+			closing := findBacktickRun(text, end, len(ticks))
+			if closing < 0 {
+				buf = append(buf, ticks...)
+				i = end
+				continue
+			}
+			flush()
+			code := strings.Replace(text[end:closing], "\n", " ", -1)
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			list.push(&inlineItem{inline: &Inline{Kind: CodeInline, Text: code}})
+			i = closing + len(ticks)
+			continue
+		case c == '*' || c == '_':
+			end := i
+			for end < len(text) && text[end] == c {
+				end++
+			}
+			flush()
+			list.push(&inlineItem{run: newDelimiterRun(text, i, end)})
+			i = end
+			continue
+		}
+		buf = append(buf, c)
+		i++
+	}
+	flush()
+	// Trailing spaces at the end of a paragraph are dropped.
+	if list.tail != nil && list.tail.inline != nil && list.tail.inline.Kind == TextInline {
+		list.tail.inline.Text = strings.TrimRight(list.tail.inline.Text, " ")
+	}
+	processEmphasis(&list)
+	return flatten(list.head, nil)
+}
+
+// findBacktickRun returns the index of the next run of exactly n backticks
+// at or after start, or -1 if there is none.
+func findBacktickRun(text string, start, n int) int {
+	for i := start; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		end := i
+		for end < len(text) && text[end] == '`' {
+			end++
+		}
+		if end-i == n {
+			return i
+		}
+		i = end
+	}
+	return -1
+}
+
+// closerKind is the kind of a closing delimiter run that decides which openers it
+// may match: its character, whether it can open too, and its length mod 3.
+type closerKind struct {
+	char    byte
+	canOpen bool
+	mod3    int
+}
+
+// processEmphasis matches delimiter runs, replacing the items between each
+// matched opener and closer with an emphasis or strong inline.
+func processEmphasis(list *inlineList) {
+	// openersBottom is, for each kind of closer, the position of the first item that
+	// may still be its opener. Openers are not looked for again below it, as in
+	// CommonMark's algorithm, so that each closer does not search the whole list.
+	openersBottom := make(map[closerKind]int)
+	closer := list.head
+	for closer != nil {
+		run := closer.run
+		if run == nil || !run.canClose || run.count == 0 {
+			closer = closer.next
+			continue
+		}
+		kind := closerKind{run.char, run.canOpen, run.original % 3}
+		var opener *inlineItem
+		for item := closer.prev; item != nil && item.position >= openersBottom[kind]; item = item.prev {
+			r := item.run
+			if r == nil || r.char != run.char || !r.canOpen || r.count == 0 {
+				continue
+			}
+			// The "rule of three"
+			if (r.canClose || run.canOpen) && (r.original+run.original)%3 == 0 &&
+				(r.original%3 != 0 || run.original%3 != 0) {
+				continue
+			}
+			opener = item
+			break
+		}
+		if opener == nil {
+			openersBottom[kind] = closer.position
+			closer = closer.next
+			continue
+		}
+		used := 1
+		inlineKind := EmphasisInline
+		if opener.run.count >= 2 && run.count >= 2 {
+			used = 2
+			inlineKind = StrongInline
+		}
+		opener.run.count -= used
+		run.count -= used
+		emphasis := &inlineItem{inline: &Inline{Kind: inlineKind, Children: flatten(opener.next, closer)},
+			position: opener.position}
+		emphasis.prev = opener
+		emphasis.next = closer
+		opener.next = emphasis
+		closer.prev = emphasis
+		if opener.run.count == 0 {
+			list.remove(opener)
+		}
+		if run.count == 0 {
+			next := closer.next
+			list.remove(closer)
+			closer = next
+		}
+	}
+}
+
+// remove takes an item out of the list.
+func (l *inlineList) remove(item *inlineItem) {
+	if item.prev == nil {
+		l.head = item.next
+	} else {
+		item.prev.next = item.next
+	}
+	if item.next == nil {
+		l.tail = item.prev
+	} else {
+		item.next.prev = item.prev
+	}
+}
+
+// flatten returns the inlines for the items from first up to, but not including, last.
+// Unmatched delimiters become text.
+func flatten(first, last *inlineItem) []*Inline {
+	var inlines []*Inline
+	var text bytes.Buffer
+	for item := first; item != last; item = item.next {
+		switch {
+		case item.run != nil:
+			for i := 0; i < item.run.count; i++ {
+				text.WriteByte(item.run.char)
+			}
+		case item.inline.Kind == TextInline:
+			text.WriteString(item.inline.Text)
+		default:
+			if text.Len() > 0 {
+				inlines = append(inlines, &Inline{Kind: TextInline, Text: text.String()})
+				text.Reset()
+			}
+			inlines = append(inlines, item.inline)
+		}
+	}
+	if text.Len() > 0 {
+		inlines = append(inlines, &Inline{Kind: TextInline, Text: text.String()})
+	}
+	return inlines
+}
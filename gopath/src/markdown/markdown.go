@@ -0,0 +1,241 @@
+// package markdown parses a subset of CommonMark into a tree of blocks.
+//
+// The subset covers ATX and setext headings, paragraphs, block quotes,
+// bullet and ordered lists, thematic breaks (horizontal rules), and the
+// inlines emphasis, strong emphasis, code spans, backslash escapes and
+// hard line breaks.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockKind is the "enum" type for kinds of blocks.
+type BlockKind int
+
+// an enumeration for block kinds.
+const (
+	_ BlockKind = iota
+	DocumentBlock
+	ParagraphBlock
+	HeadingBlock
+	BlockQuoteBlock
+	ListBlock
+	ItemBlock
+	RuleBlock
+)
+
+// Block is a node in the block tree.
+type Block struct {
+	Kind BlockKind
+	// Level is the level of a heading, from 1 to 6.
+	Level int
+	// Ordered is true for an ordered list, whose items are numbered from Start.
+	Ordered bool
+	Start   int
+	// Inlines is the content of a paragraph or heading.
+	Inlines []*Inline
+	// Children are the blocks inside a document, block quote, list or list item.
+	Children []*Block
+}
+
+var (
+	atxHeadingRE  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))??(?:[ \t]+#+)?[ \t]*$`)
+	setextRE      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	ruleRE        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	blockQuoteRE  = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemRE    = regexp.MustCompile(`^ {0,3}([-+*]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	blankRE       = regexp.MustCompile(`^[ \t]*$`)
+	leadingSpaces = regexp.MustCompile(`^ *`)
+)
+
+// Parse parses Markdown text, returning a block of kind DocumentBlock.
+func Parse(text string) *Block {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\t", "    ", -1)
+	lines := strings.Split(text, "\n")
+	return &Block{Kind: DocumentBlock, Children: parseBlocks(lines)}
+}
+
+// isBlank returns true if a line has only whitespace.
+func isBlank(line string) bool {
+	return blankRE.MatchString(line)
+}
+
+// startsBlock returns true if a line starts a block other than a paragraph,
+// and so cannot be the lazy continuation of a paragraph.
+func startsBlock(line string) bool {
+	return atxHeadingRE.MatchString(line) || ruleRE.MatchString(line) ||
+		blockQuoteRE.MatchString(line) || listItemRE.MatchString(line)
+}
+
+// listMarker describes the marker of a list item.
+type listMarker struct {
+	// bullet is the bullet character, or the delimiter ('.' or ')') for an ordered list.
+	bullet  byte
+	ordered bool
+	start   int
+	// indent is the column at which the item's content starts.
+	indent int
+}
+
+// parseListMarker returns the marker of a list item, and the rest of the line.
+// ok is false if the line does not start a list item.
+func parseListMarker(line string) (marker listMarker, rest string, ok bool) {
+	m := listItemRE.FindStringSubmatch(line)
+	if m == nil {
+		return marker, "", false
+	}
+	text := m[1]
+	if last := text[len(text)-1]; last == '.' || last == ')' {
+		marker.ordered = true
+		marker.bullet = last
+		marker.start, _ = strconv.Atoi(text[:len(text)-1])
+	} else {
+		marker.bullet = text[0]
+	}
+	rest = line[len(m[0]):]
+	marker.indent = len(m[0])
+	if isBlank(rest) {
+		// An item starting with a blank line has its content one space after the marker.
+		marker.indent = len(strings.TrimRight(m[0], " ")) + 1
+	} else if spaces := len(m[0]) - len(strings.TrimRight(m[0], " ")); spaces > 4 {
+		// Five or more spaces means the content starts with indented text.
+		marker.indent -= spaces - 1
+		rest = line[marker.indent:]
+	}
+	return marker, rest, true
+}
+
+// stripIndent removes up to n leading spaces from a line.
+// ok is false if the line has fewer than n leading spaces.
+func stripIndent(line string, n int) (string, bool) {
+	spaces := len(leadingSpaces.FindString(line))
+	if spaces >= n {
+		return line[n:], true
+	}
+	return line[spaces:], false
+}
+
+// parseBlocks parses lines into a list of blocks.
+func parseBlocks(lines []string) []*Block {
+	var blocks []*Block
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			text := strings.Join(para, "\n")
+			blocks = append(blocks, &Block{Kind: ParagraphBlock, Inlines: parseInlines(text)})
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			flush()
+			i++
+			continue
+		}
+		if m := setextRE.FindStringSubmatch(line); m != nil && len(para) > 0 {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			text := strings.Join(para, "\n")
+			para = nil
+			blocks = append(blocks, &Block{Kind: HeadingBlock, Level: level, Inlines: parseInlines(text)})
+			i++
+			continue
+		}
+		if ruleRE.MatchString(line) {
+			flush()
+			blocks = append(blocks, &Block{Kind: RuleBlock})
+			i++
+			continue
+		}
+		if m := atxHeadingRE.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, &Block{Kind: HeadingBlock, Level: len(m[1]), Inlines: parseInlines(m[2])})
+			i++
+			continue
+		}
+		if blockQuoteRE.MatchString(line) {
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				line := lines[i]
+				if loc := blockQuoteRE.FindStringIndex(line); loc != nil {
+					quoted = append(quoted, line[loc[1]:])
+				} else if !isBlank(line) && !startsBlock(line) && len(quoted) > 0 && !isBlank(quoted[len(quoted)-1]) {
+					// a lazy continuation line
+					quoted = append(quoted, line)
+				} else {
+					break
+				}
+			}
+			blocks = append(blocks, &Block{Kind: BlockQuoteBlock, Children: parseBlocks(quoted)})
+			continue
+		}
+		if marker, rest, ok := parseListMarker(line); ok {
+			// An ordered list may only interrupt a paragraph if it starts with 1,
+			// and an empty item may not interrupt one at all.
+			interrupts := (!marker.ordered || marker.start == 1) && !isBlank(rest)
+			if len(para) == 0 || interrupts {
+				flush()
+				var list *Block
+				list, i = parseList(lines, i)
+				blocks = append(blocks, list)
+				continue
+			}
+		}
+		para = append(para, strings.TrimLeft(line, " "))
+		i++
+	}
+	flush()
+	return blocks
+}
+
+// parseList parses a list starting at lines[start], which must start a list item.
+// It returns the list and the index of the first line after it.
+func parseList(lines []string, start int) (*Block, int) {
+	first, _, _ := parseListMarker(lines[start])
+	list := &Block{Kind: ListBlock, Ordered: first.ordered, Start: first.start}
+	i := start
+	for i < len(lines) {
+		marker, rest, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.bullet != first.bullet || ruleRE.MatchString(lines[i]) {
+			break
+		}
+		item := []string{rest}
+		i++
+		lastBlank := isBlank(rest)
+		for ; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				item = append(item, "")
+				lastBlank = true
+				continue
+			}
+			if stripped, ok := stripIndent(line, marker.indent); ok {
+				item = append(item, stripped)
+			} else if !lastBlank && !startsBlock(line) {
+				// a lazy continuation line
+				item = append(item, line)
+			} else {
+				break
+			}
+			lastBlank = false
+		}
+		// Trailing blank lines belong between items, not inside them.
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+		}
+		list.Children = append(list.Children, &Block{Kind: ItemBlock, Children: parseBlocks(item)})
+	}
+	// Leave the blank lines after the list for the caller.
+	for i > start && isBlank(lines[i-1]) {
+		i--
+	}
+	return list, i
+}
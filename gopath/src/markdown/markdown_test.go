@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// inlineString returns a compact representation of inlines, for comparison.
+func inlineString(inlines []*Inline) string {
+	s := ""
+	for _, inline := range inlines {
+		switch inline.Kind {
+		case TextInline:
+			s += inline.Text
+		case SoftBreakInline:
+			s += "~"
+		case HardBreakInline:
+			s += "|"
+		case CodeInline:
+			s += "[code:" + inline.Text + "]"
+		case EmphasisInline:
+			s += "[em:" + inlineString(inline.Children) + "]"
+		case StrongInline:
+			s += "[strong:" + inlineString(inline.Children) + "]"
+		}
+	}
+	return s
+}
+
+// blockString returns a compact representation of a block tree, for comparison.
+func blockString(block *Block) string {
+	var children []string
+	for _, child := range block.Children {
+		children = append(children, blockString(child))
+	}
+	inner := strings.Join(children, " ")
+	switch block.Kind {
+	case DocumentBlock:
+		return inner
+	case ParagraphBlock:
+		return "p(" + inlineString(block.Inlines) + ")"
+	case HeadingBlock:
+		return fmt.Sprintf("h%d(%s)", block.Level, inlineString(block.Inlines))
+	case BlockQuoteBlock:
+		return "quote(" + inner + ")"
+	case ListBlock:
+		if block.Ordered {
+			return fmt.Sprintf("ol%d(%s)", block.Start, inner)
+		}
+		return "ul(" + inner + ")"
+	case ItemBlock:
+		return "li(" + inner + ")"
+	case RuleBlock:
+		return "hr"
+	}
+	return "?"
+}
+
+func TestInlines(t *testing.T) {
+	type data struct {
+		Text     string
+		Expected string
+	}
+	testData := []data{data{"plain text", "plain text"},
+		data{"*em* and _em_", "[em:em] and [em:em]"},
+		data{"**strong** and __strong__", "[strong:strong] and [strong:strong]"},
+		data{"***both***", "[em:[strong:both]]"},
+		data{"*em **strong** em*", "[em:em [strong:strong] em]"},
+		data{"snake_case_name", "snake_case_name"},
+		data{"2 * 3 * 4", "2 * 3 * 4"},
+		data{"*unclosed", "*unclosed"},
+		data{"**open *em*", "**open [em:em]"},
+		data{"`code *not em*`", "[code:code *not em*]"},
+		data{"`` a ` b ``", "[code:a ` b]"},
+		data{"`unclosed", "`unclosed"},
+		data{`\*not em\*`, "*not em*"},
+		data{"soft\nbreak", "soft~break"},
+		data{"hard  \nbreak", "hard|break"},
+		data{"hard\\\nbreak", "hard|break"},
+		data{"*foo**bar*", "[em:foo**bar]"},
+		data{"*foo**bar**baz*", "[em:foo[strong:bar]baz]"},
+		data{"a* b* *c*", "a* b* [em:c]"},
+		data{"_a *b_ c*", "[em:a *b] c*"}}
+	for _, d := range testData {
+		if s := inlineString(parseInlines(d.Text)); s != d.Expected {
+			t.Errorf("%q parsed as %q", d.Text, s)
+		}
+	}
+}
+
+func TestUnmatchedDelimiters(t *testing.T) {
+	// Each closer looks back for openers only as far as the last one that failed to.
+	text := strings.Repeat("a* ", 20000)
+	if s := inlineString(parseInlines(text)); s != strings.TrimRight(text, " ") {
+		t.Errorf("unmatched delimiters parsed as %q", s[:20])
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	type data struct {
+		Text     string
+		Expected string
+	}
+	testData := []data{data{"one\ntwo\n\nthree", "p(one~two) p(three)"},
+		data{"# Title #\n## Sub\n####### no", "h1(Title) h2(Sub) p(####### no)"},
+		data{"Title\n=====\nSub\n---", "h1(Title) h2(Sub)"},
+		data{"a\n\n***\n\n- - -", "p(a) hr hr"},
+		data{"> quoted\nlazy\n>\n> more", "quote(p(quoted~lazy) p(more))"},
+		data{"- a\n- b\n\n- c", "ul(li(p(a)) li(p(b)) li(p(c)))"},
+		data{"- a\n  - b\n- c", "ul(li(p(a) ul(li(p(b)))) li(p(c)))"},
+		data{"3. three\n4. four", "ol3(li(p(three)) li(p(four)))"},
+		data{"- a\n* b", "ul(li(p(a))) ul(li(p(b)))"},
+		data{"text\n2. not a list", "p(text~2. not a list)"},
+		data{"- item\n\n  second para\nafter", "ul(li(p(item) p(second para~after)))"},
+		data{"- item\n\nafter", "ul(li(p(item))) p(after)"},
+		data{"> - quoted list", "quote(ul(li(p(quoted list))))"}}
+	for _, d := range testData {
+		if s := blockString(Parse(d.Text)); s != d.Expected {
+			t.Errorf("%q parsed as %q", d.Text, s)
+		}
+	}
+}
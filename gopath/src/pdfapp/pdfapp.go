@@ -12,25 +12,45 @@ The routes should be, but are not yet
  - PUT /document/{id}/		Update an existing document with json in body.
  - DELETE /document/{id}/	Delete an existing document.
  - GET /pdf/{id}/			Get the pdf for an existing document.
+ - GET /ps/{id}/				Get a document as PostScript.
+ - GET /eps/{id}/{page}/	Get a page of a document as Encapsulated PostScript.
+ - GET /png/{id}/{page}/	Get a page of a document as a PNG image, at ?dpi= (default 96).
+ - GET /svg/{id}/{page}/	Get a page of a document as an SVG image.
+ - GET /fonts/				Get a json list of the installed font families, with their faces.
+ - POST /fonts/				List the installed font families again, and get them.
+ - GET /fonts/{family}/		Get an installed font family, with its faces, in json form.
+ - POST /font/				Upload a TrueType or OpenType font file given in the body.
+ - POST /image/				Upload a PNG or JPEG image given in the body.
+ - GET /image/{id}/			Get an uploaded image.
+The pdf, ps, eps, png and svg routes also take ?watermark=text, which stamps the
+output with text, such as DRAFT, as well as the document's own watermarks.
 Perhaps these should also switch on Accept headers.
 */
 package main
 
 import (
+	"bytes"
 	"code.google.com/p/gorilla/mux"
+	"crypto/sha1"
 	"db"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"hyphenation"
+	"io/ioutil"
 	"local/document"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
-	"sort"
+	"strconv"
+	"strings"
 	"textproc"
+	"time"
+	"unicode"
 	"web"
 )
 
@@ -40,6 +60,9 @@ var TemplateDir string
 // StaticDir is the runtime directory for static files
 var StaticDir string
 
+// FontDir is the runtime directory for uploaded fonts
+var FontDir string
+
 // DB is the database
 var DB document.DB
 
@@ -68,8 +91,7 @@ func editHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	fonts := textproc.ListFontFamilies()
-	sort.Strings(fonts)
+	fonts := textproc.Fonts.FamilyNames()
 	defaultDoc := document.DefaultDocument()
 	defaultDocJSON, err := json.Marshal(defaultDoc)
 	if err != nil {
@@ -102,32 +124,595 @@ func editHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// pdfhandler makes a pdf file out of the information it is passed.
-func pdfhandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
-	fmt.Printf("%s\n", r.Header.Get("Accept"))
-	header := w.Header()
-
-	id := assignId(r)
-	doc, err := DB.Fetch(id)
-	if err != nil {
-		web.Error(w, err.Error(), http.StatusNotFound)
-		return
-	}
-
-	header.Set("Content-Type", "application/pdf")
-	//header.Set("Content-Disposition", "attachment;filename=foo.pdf")
-	pdf := textproc.MakePDFStreamTextObject(w, 8.5*72, 11*72)
-	defer pdf.Close()
+// typesettingProps returns the typesetting properties for a document.
+func typesettingProps(doc *document.Document) textproc.TypesettingProps {
 	props := textproc.TypesettingProps{}
 	props.Fontname = doc.Font
+	props.FontWeight, _ = document.ParseFontWeight(doc.FontWeight)
+	props.FontStyle = doc.FontStyle
+	props.FontStretch = doc.FontStretch
+	props.FontVariant = doc.FontVariant
+	props.FontFeatures, _ = document.ParseFontFeatures(doc.FontFeatures)
+	props.LetterSpacing = doc.LetterSpacing.Points()
+	props.Language = doc.Language
+	props.Direction = doc.Direction
+	props.Alignment = doc.Alignment
+	props.LastLine = doc.LastLineAlignment
 	props.Fontsize = doc.FontSize.Points()
 	props.Baselineskip = doc.BaselineSkip.Points()
 	props.PageWidth = doc.PageWidth.Points()
 	props.PageHeight = doc.PageHeight.Points()
 	props.LeftMargin = doc.LeftMargin.Points()
 	props.RightMargin = doc.RightMargin.Points()
-	pdf.WriteAt(doc.Text, props, props.LeftMargin, doc.TopMargin.Points()+props.Fontsize)
+	props.TopMargin = doc.TopMargin.Points()
+	props.BottomMargin = doc.BottomMargin.Points()
+	props.ParSkip = doc.ParSkip.Points()
+	props.OptimalBreaking = doc.LineBreaking == document.OptimalBreaking
+	props.Tolerance = float64(doc.Tolerance)
+	props.Looseness = doc.Looseness
+	props.HyphenPenalty = float64(doc.HyphenPenalty)
+	props.ExHyphenPenalty = float64(doc.ExHyphenPenalty)
+	props.Columns = doc.Columns
+	props.ColumnSep = doc.ColumnSep.Points()
+	props.ColumnRule = doc.ColumnRule
+	props.Widows = doc.Widows
+	props.Orphans = doc.Orphans
+	props.WidowPenalty = float64(doc.WidowPenalty)
+	props.OrphanPenalty = float64(doc.OrphanPenalty)
+	props.TextColor = color(doc.TextColor)
+	props.RuleColor = color(doc.RuleColor)
+	props.ColumnRuleColor = color(doc.ColumnRuleColor)
+	props.BaselineGrid = doc.BaselineGrid
+	if doc.Direction == document.RightToLeft {
+		// The margins of a right-to-left document are mirrored, so that the left
+		// margin is where its lines start.
+		props.LeftMargin, props.RightMargin = props.RightMargin, props.LeftMargin
+	}
+	return props
+}
+
+// color returns a document color as a textproc color. An unset color is black.
+func color(c document.Color) textproc.Color {
+	r, g, b := c.RGB()
+	return textproc.Color{R: r, G: g, B: b}
+}
+
+// validateMarkup checks the markup of each block, returning an error
+// giving the position of the first bad markup in the document text.
+func validateMarkup(blocks []document.Block) error {
+	for _, block := range blocks {
+		if !block.Markup {
+			continue
+		}
+		for _, note := range block.Notes {
+			if err := textproc.ValidateMarkup(note.Text); err != nil {
+				return fmt.Errorf("Markup error in footnote %d: %s", note.Number, err.Error())
+			}
+		}
+		err := textproc.ValidateMarkup(block.Text)
+		if err == nil {
+			continue
+		}
+		if merr, ok := err.(*textproc.MarkupError); ok && merr.Offset >= 0 {
+			if line, column := block.Position(merr.Offset); line > 0 {
+				return fmt.Errorf("Markup error at line %d, column %d: %s", line, column, merr.Message)
+			}
+		}
+		return errors.New("Markup error: " + err.Error())
+	}
+	return nil
+}
+
+// hyphenate returns the text of a block with soft hyphens inserted.
+func hyphenate(patterns *hyphenation.Patterns, doc *document.Document, block document.Block) string {
+	left, right := doc.HyphenMins()
+	if block.Markup {
+		return patterns.HyphenateMarkup(block.Text, left, right)
+	}
+	return patterns.HyphenateText(block.Text, left, right)
+}
+
+// runningHead returns a header or footer, with its left and right slots
+// swapped in a right-to-left document.
+func runningHead(doc *document.Document, head document.RunningHead) textproc.RunningHead {
+	if doc.Direction == document.RightToLeft {
+		head.Left, head.Right = head.Right, head.Left
+	}
+	return textproc.RunningHead(head)
+}
+
+// pageHeads returns the header and footer of a document's pages.
+func pageHeads(doc *document.Document) textproc.PageHeads {
+	return textproc.PageHeads{
+		Header:            runningHead(doc, doc.Header),
+		Footer:            runningHead(doc, doc.Footer),
+		HeaderSkip:        doc.HeaderSkip.Points(),
+		FooterSkip:        doc.FooterSkip.Points(),
+		SuppressFirstPage: doc.SuppressFirstPageHeads,
+		Title:             doc.Title,
+		Date:              time.Now().Format("January 2, 2006"),
+	}
+}
+
+// watermarks returns the watermarks of a document's pages.
+func watermarks(doc *document.Document) []textproc.Watermark {
+	var marks []textproc.Watermark
+	for _, w := range doc.Watermarks {
+		size := w.FontSize.Points()
+		if size == 0 {
+			size = document.DefaultWatermarkSize
+		}
+		ranges, _ := document.ParsePageRanges(w.Pages)
+		marks = append(marks, textproc.Watermark{
+			Text:     w.Text,
+			Fontname: w.Font,
+			Fontsize: size,
+			Color:    color(w.Color),
+			Opacity:  w.Opacity,
+			Angle:    w.Angle,
+			Center:   watermarkCenter(doc, w.Placement),
+			Pages:    onPages(ranges),
+			Over:     w.Over,
+		})
+	}
+	return marks
+}
+
+// watermarkCenter returns where a watermark with the given placement is centered
+// on a page, or nil for the middle of the page.
+func watermarkCenter(doc *document.Document, placement string) func(width, height float64) (x, y float64) {
+	top := doc.TopMargin.Points()
+	bottom := doc.BottomMargin.Points()
+	switch placement {
+	case document.WatermarkTop:
+		return func(width, height float64) (x, y float64) {
+			return width / 2, top / 2
+		}
+	case document.WatermarkBottom:
+		return func(width, height float64) (x, y float64) {
+			return width / 2, height - bottom/2
+		}
+	}
+	return nil
+}
+
+// onPages returns a function that is true for the pages in ranges, or nil for all
+// pages if there are no ranges.
+func onPages(ranges []document.PageRange) func(page int) bool {
+	if len(ranges) == 0 {
+		return nil
+	}
+	return func(page int) bool {
+		for _, r := range ranges {
+			if r.Contains(page) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// footnotes returns the footnotes of a block.
+func footnotes(block document.Block) []textproc.Footnote {
+	var notes []textproc.Footnote
+	for _, note := range block.Notes {
+		notes = append(notes, textproc.Footnote{Mark: strconv.Itoa(note.Number), Text: note.Text, Markup: block.Markup})
+	}
+	return notes
+}
+
+// alignment returns the alignment and last line alignment of a block,
+// which are the document's unless the block overrides them.
+func alignment(doc *document.Document, block document.Block) (align, lastLine string) {
+	align, lastLine = doc.Alignment, doc.LastLineAlignment
+	if block.Alignment != "" {
+		align = block.Alignment
+	}
+	if block.LastLine != "" {
+		lastLine = block.LastLine
+	}
+	return align, lastLine
+}
+
+// links returns the links of a block.
+func links(block document.Block) []textproc.Link {
+	var links []textproc.Link
+	for _, link := range block.Links {
+		if anchor, ok := link.Internal(); ok {
+			links = append(links, textproc.Link{Dest: anchor})
+		} else {
+			links = append(links, textproc.Link{URI: link.Target})
+		}
+	}
+	return links
+}
+
+// fetchImages returns the images of the image blocks, by Id.
+func fetchImages(blocks []document.Block) (map[db.Id]document.Image, error) {
+	images := make(map[db.Id]document.Image)
+	for _, block := range blocks {
+		if block.Kind != document.ImageBlock {
+			continue
+		}
+		if _, ok := images[block.Image]; ok {
+			continue
+		}
+		img, err := DB.FetchImage(block.Image)
+		if err != nil {
+			return nil, errors.New("No image " + block.Image.String())
+		}
+		// Images stored before their sizes were have theirs read here.
+		if err := img.Measure(); err != nil {
+			return nil, err
+		}
+		images[block.Image] = img
+	}
+	return images, nil
+}
+
+// typeset writes the blocks of a document to pdf.
+func typeset(pdf *textproc.StreamTextObject, doc *document.Document, blocks []document.Block,
+	images map[db.Id]document.Image, props textproc.TypesettingProps, patterns *hyphenation.Patterns) error {
+	first := true
+	for _, block := range blocks {
+		switch block.Kind {
+		case document.PageBreakBlock:
+			if block.PageWidth.Points() > 0 {
+				props.PageWidth = block.PageWidth.Points()
+				props.PageHeight = block.PageHeight.Points()
+			}
+			pdf.NewPage(props.PageWidth, props.PageHeight)
+		case document.ParagraphBlock:
+			props.Markup = block.Markup
+			props.LeftIndent = float64(block.Depth) * 2 * props.Fontsize
+			props.Label = block.Label
+			props.Alignment, props.LastLine = alignment(doc, block)
+			props.Notes = footnotes(block)
+			props.Links = links(block)
+			props.Anchor = ""
+			switch {
+			case block.Label != "":
+				props.Indent = 0
+			case first:
+				props.Indent = doc.FirstParagraphIndent.Points()
+			default:
+				props.Indent = doc.ParIndent.Points()
+			}
+			first = false
+			text := block.Text
+			if patterns != nil {
+				text = hyphenate(patterns, doc, block)
+			}
+			pdf.WriteParagraph(text, props)
+		case document.HeadingBlock:
+			props.Markup = block.Markup
+			props.Alignment, props.LastLine = alignment(doc, block)
+			props.Notes = footnotes(block)
+			props.Links = links(block)
+			props.Anchor = block.Anchor
+			pdf.WriteHeading(block.Text, block.Level, props)
+			first = true
+		case document.RuleBlock:
+			props.LeftIndent = 0
+			pdf.WriteRule(props)
+			first = true
+		case document.ImageBlock:
+			props.LeftIndent = 0
+			img := images[block.Image]
+			err := pdf.WriteImage(img.Data, img.ContentType, block.ImageWidth.Points(), block.ImageHeight.Points(), props)
+			if err != nil {
+				return err
+			}
+			first = true
+		}
+	}
+	return nil
+}
+
+// typesetting is a document ready to be typeset, with everything it needs.
+type typesetting struct {
+	doc      document.Document
+	blocks   []document.Block
+	images   map[db.Id]document.Image
+	props    textproc.TypesettingProps
+	patterns *hyphenation.Patterns
+	heads    textproc.PageHeads
+	// sizes are the sizes of the pages, found when they are counted.
+	sizes []textproc.PageSize
+}
+
+// prepare fetches the document of the request and checks that it can be typeset.
+// If not, it writes an error to w and returns nil.
+func prepare(w http.ResponseWriter, r *http.Request) *typesetting {
+	id := assignId(r)
+	doc, err := DB.Fetch(id)
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if text := r.FormValue("watermark"); text != "" {
+		doc.Watermarks = append(doc.Watermarks, document.DefaultWatermark(text))
+	}
+	if err := doc.Validate(); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	ts := &typesetting{doc: doc}
+	ts.props = typesettingProps(&ts.doc)
+	if ts.props.PageWidth <= 0 || ts.props.PageHeight <= 0 {
+		web.Error(w, "Page sizes must be positive", http.StatusBadRequest)
+		return nil
+	}
+	if ts.props.ColumnWidth() <= 0 {
+		web.Error(w, "Columns are too narrow for the page", http.StatusBadRequest)
+		return nil
+	}
+	if err := textproc.CheckFont(ts.props); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	for _, mark := range ts.doc.Watermarks {
+		if _, ok := textproc.Fonts.Family(mark.Font); mark.Font != "" && !ok {
+			web.Error(w, "Font family "+mark.Font+" is not installed", http.StatusBadRequest)
+			return nil
+		}
+	}
+	ts.blocks, err = ts.doc.Blocks()
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err := validateMarkup(ts.blocks); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if ts.doc.Hyphenate {
+		ts.patterns, err = hyphenation.ForLanguage(ts.doc.Language)
+		if err != nil {
+			web.Error(w, err.Error(), http.StatusBadRequest)
+			return nil
+		}
+	}
+
+	ts.images, err = fetchImages(ts.blocks)
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	ts.heads = pageHeads(&ts.doc)
+	if ts.heads.NeedsPageCount() {
+		if err := ts.count(); err != nil {
+			web.Error(w, err.Error(), http.StatusBadRequest)
+			return nil
+		}
+	}
+	return ts
+}
+
+// count typesets the document once without output, to count its pages for the
+// heads and to find their sizes.
+func (ts *typesetting) count() error {
+	counter := textproc.MakePDFStreamTextObject(ioutil.Discard, ts.props.PageWidth, ts.props.PageHeight)
+	defer counter.Close()
+	err := typeset(counter, &ts.doc, ts.blocks, ts.images, ts.props, ts.patterns)
+	ts.heads.Pages = counter.PageCount()
+	ts.sizes = counter.PageSizes()
+	return err
+}
+
+// write typesets the document to out, and closes it. Only one document is typeset
+// at a time, so out writes to a buffer, which is sent once it is closed if there
+// was no error.
+func (ts *typesetting) write(out *textproc.StreamTextObject) error {
+	defer out.Close()
+	out.SetPageHeads(ts.heads, ts.props)
+	out.SetWatermarks(watermarks(&ts.doc), ts.props)
+	if !ts.doc.PageColor.IsZero() {
+		out.SetPageColor(color(ts.doc.PageColor))
+	}
+	if ts.doc.ShowBaselineGrid {
+		out.ShowBaselineGrid(ts.props)
+	}
+	return typeset(out, &ts.doc, ts.blocks, ts.images, ts.props, ts.patterns)
+}
+
+// pageNumber returns the page of the request, counting the pages if they have
+// not been, or writes an error to w and returns 0 if the document has no such page.
+func (ts *typesetting) pageNumber(w http.ResponseWriter, r *http.Request) int {
+	page, err := strconv.Atoi(mux.Vars(r)["Page"])
+	if err != nil || page < 1 {
+		web.Error(w, "No such page", http.StatusNotFound)
+		return 0
+	}
+	if ts.sizes == nil {
+		if err := ts.count(); err != nil {
+			web.Error(w, err.Error(), http.StatusBadRequest)
+			return 0
+		}
+	}
+	if page > ts.heads.Pages {
+		web.Error(w, "No such page", http.StatusNotFound)
+		return 0
+	}
+	return page
+}
+
+// defaultDPI is the resolution of PNG images of pages if none is asked for.
+const defaultDPI = 96
+
+// parseDPI returns the resolution given by the dpi query parameter, or defaultDPI.
+func parseDPI(s string) (float64, error) {
+	if s == "" {
+		return defaultDPI, nil
+	}
+	dpi, err := strconv.ParseFloat(s, 64)
+	if err != nil || dpi <= 0 || dpi > textproc.MaxDPI {
+		return 0, errors.New("Resolution must be between 1 and 1200 dpi")
+	}
+	return dpi, nil
+}
+
+// pdfhandler makes a pdf file out of the information it is passed.
+func pdfhandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	fmt.Printf("%s\n", r.Header.Get("Accept"))
+	ts := prepare(w, r)
+	if ts == nil {
+		return
+	}
+	var buf bytes.Buffer
+	pdf := textproc.MakePDFStreamTextObject(&buf, ts.props.PageWidth, ts.props.PageHeight)
+	pdf.SetMetadata(textproc.Metadata{
+		Title:    ts.doc.Title,
+		Author:   ts.doc.Author,
+		Subject:  ts.doc.Subject,
+		Keywords: ts.doc.Keywords,
+		Creator:  ts.doc.Creator,
+		Created:  ts.doc.Created,
+		Modified: ts.doc.Modified,
+	})
+	if err := ts.write(pdf); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", contentDisposition(ts.doc.Title, ".pdf"))
+	w.Write(buf.Bytes())
+}
+
+// filename returns a file name made from a document's title and the extension ext,
+// keeping only letters, digits, '-', '_' and '.', with spaces as '_'.
+// An empty title gives "document".
+func filename(title, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '_'
+		case r == '-' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return r
+		}
+		return -1
+	}, strings.TrimSpace(title))
+	name = strings.Trim(name, "._")
+	if name == "" {
+		name = "document"
+	}
+	return name + ext
+}
+
+// contentDisposition returns a Content-Disposition header value for showing a
+// file inline, named after a document's title. The name is given as ASCII, and
+// in full as UTF-8 if it has other characters.
+func contentDisposition(title, ext string) string {
+	name := filename(title, ext)
+	ascii := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '_'
+		}
+		return r
+	}, name)
+	value := `inline; filename="` + ascii + `"`
+	if ascii != name {
+		value += "; filename*=UTF-8''" + url.QueryEscape(name)
+	}
+	return value
+}
+
+// psHandler makes a PostScript file of a document.
+func psHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	ts := prepare(w, r)
+	if ts == nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := ts.write(textproc.MakePSStreamTextObject(&buf, ts.props.PageWidth, ts.props.PageHeight)); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/postscript")
+	w.Write(buf.Bytes())
+}
+
+// epsHandler returns a page of a document as an Encapsulated PostScript file.
+func epsHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	ts := prepare(w, r)
+	if ts == nil {
+		return
+	}
+	page := ts.pageNumber(w, r)
+	if page == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	if err := ts.write(textproc.MakeEPSPageTextObject(&buf, page, ts.props.PageWidth, ts.props.PageHeight)); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/postscript")
+	w.Write(buf.Bytes())
+}
+
+// svgHandler returns a page of a document as an SVG image.
+func svgHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	ts := prepare(w, r)
+	if ts == nil {
+		return
+	}
+	page := ts.pageNumber(w, r)
+	if page == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	if err := ts.write(textproc.MakeSVGPageTextObject(&buf, page, ts.props.PageWidth, ts.props.PageHeight)); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(buf.Bytes())
+}
+
+// pngHandler returns a page of a document as a PNG image, at the resolution
+// given by the dpi query parameter.
+func pngHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	dpi, err := parseDPI(r.FormValue("dpi"))
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ts := prepare(w, r)
+	if ts == nil {
+		return
+	}
+	page := ts.pageNumber(w, r)
+	if page == 0 {
+		return
+	}
+	size := ts.sizes[page-1]
+	if err := textproc.CheckPNGPage(dpi, size.Width, size.Height); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var buf bytes.Buffer
+	out, err := textproc.MakePNGPageTextObject(&buf, page, dpi, ts.props.PageWidth, ts.props.PageHeight)
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := ts.write(out); err != nil {
+		web.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := out.Err(); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
 }
 
 func writeDoc(w http.ResponseWriter, doc *document.Document) {
@@ -143,6 +728,8 @@ func postDocHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
 	doc := document.Document{}
 	json.NewDecoder(r.Body).Decode(&doc)
+	// The times a document was created and modified are kept by the server.
+	doc.Created, doc.Modified = time.Time{}, time.Time{}
 	err := DB.Add(&doc)
 	if err != nil {
 		// Try to figure out what the error was
@@ -158,6 +745,7 @@ func putDocHandler(w http.ResponseWriter, r *http.Request) {
 
 	doc := document.Document{}
 	json.NewDecoder(r.Body).Decode(&doc)
+	doc.Created, doc.Modified = time.Time{}, time.Time{}
 
 	if !id.IsNull() {
 		doc.Id = id
@@ -187,6 +775,136 @@ func deleteDocHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// maxImageSize is the largest image that can be uploaded, in bytes.
+const maxImageSize = 16 << 20
+
+// postImageHandler stores an uploaded PNG or JPEG image, given as the request body,
+// and returns its id as json.
+func postImageHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxImageSize))
+	if err != nil {
+		web.Error(w, "Image too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	img, err := document.NewImage(data)
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err := DB.AddImage(img); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]db.Id{"id": img.Id})
+}
+
+// getImageHandler returns an uploaded image.
+func getImageHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	img, err := DB.FetchImage(assignId(r))
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Write(img.Data)
+}
+
+// writeJSON writes v as json.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// fontsHandler returns the installed font families, with their faces, as json.
+func fontsHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	writeJSON(w, textproc.Fonts.Families())
+}
+
+// refreshFontsHandler looks for fonts installed or removed since the server started
+// or last looked, and returns the installed font families as json.
+func refreshFontsHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	if err := textproc.RefreshFonts(); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, textproc.Fonts.Families())
+}
+
+// fontFamilyHandler returns an installed font family, with its faces, as json.
+func fontFamilyHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	family, ok := textproc.Fonts.Family(mux.Vars(r)["Family"])
+	if !ok {
+		web.Error(w, "No font family "+mux.Vars(r)["Family"], http.StatusNotFound)
+		return
+	}
+	writeJSON(w, family)
+}
+
+// maxFontSize is the largest font file that can be uploaded, in bytes.
+const maxFontSize = 32 << 20
+
+// postFontHandler stores an uploaded TrueType or OpenType font file, given as the
+// request body, in FontDir, and makes its fonts available. It returns the file's
+// name and faces as json.
+func postFontHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("%s %s\n", r.Method, r.URL.Path)
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxFontSize))
+	if err != nil {
+		web.Error(w, "Font too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	ext, err := textproc.FontFileExtension(data)
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	// Check the font in a file of its own before it is put among the others,
+	// where fontconfig would find it.
+	tmp, err := ioutil.TempFile("", "font")
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	faces, err := textproc.FontFileFaces(tmp.Name())
+	if err != nil {
+		web.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Files are named by their contents, so that a font uploaded again replaces itself.
+	name := fmt.Sprintf("%x%s", sha1.Sum(data), ext)
+	file := path.Join(FontDir, name)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := textproc.AddFontFile(file); err != nil {
+		web.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, struct {
+		File  string
+		Faces []textproc.FontFileFace
+	}{name, faces})
+}
+
 func staticHandler(w http.ResponseWriter, r *http.Request) {
 	filename := mux.Vars(r)["Filename"]
 	http.ServeFile(w, r, path.Join(StaticDir, filename))
@@ -221,6 +939,10 @@ func SetupDB(dbname string) document.DB {
 func MakeRouter() http.Handler {
 	r := web.MakeRouter(TemplateDir)
 	r.HandleFunc(`/pdf/{Id}/`, pdfhandler).Methods("GET")
+	r.HandleFunc(`/ps/{Id}/`, psHandler).Methods("GET")
+	r.HandleFunc(`/eps/{Id}/{Page:[0-9]+}/`, epsHandler).Methods("GET")
+	r.HandleFunc(`/png/{Id}/{Page:[0-9]+}/`, pngHandler).Methods("GET")
+	r.HandleFunc(`/svg/{Id}/{Page:[0-9]+}/`, svgHandler).Methods("GET")
 	r.HandleFunc("/static/{Filename:.*}", staticHandler).Methods("GET")
 	r.HandleFunc("/", editHandler).Methods("GET")
 
@@ -231,6 +953,14 @@ func MakeRouter() http.Handler {
 	idr.HandleFunc(`/`, getDocHandler).Methods("GET")
 	idr.HandleFunc(`/`, deleteDocHandler).Methods("DELETE")
 
+	r.HandleFunc(`/image/`, postImageHandler).Methods("POST")
+	r.HandleFunc(`/image/{Id}/`, getImageHandler).Methods("GET")
+
+	r.HandleFunc(`/fonts/`, fontsHandler).Methods("GET")
+	r.HandleFunc(`/fonts/`, refreshFontsHandler).Methods("POST")
+	r.HandleFunc(`/fonts/{Family}/`, fontFamilyHandler).Methods("GET")
+	r.HandleFunc(`/font/`, postFontHandler).Methods("POST")
+
 	r.HandleFunc(`/edit/{Id}/`, editHandler).Methods("GET")
 	r.HandleFunc(`/panic/`, panicHandler)
 	return r
@@ -239,7 +969,19 @@ func MakeRouter() http.Handler {
 func SetPaths(topdir string) {
 	TemplateDir = path.Join(topdir, "templates")
 	StaticDir = path.Join(topdir, "static")
+	FontDir = path.Join(topdir, "fonts")
 	web.SetTemplateDir(TemplateDir)
+	hyphenation.SetPatternDir(path.Join(topdir, "hyphenation"))
+}
+
+// SetupFonts makes the uploaded fonts available, creating their directory if need be.
+func SetupFonts() {
+	if err := os.MkdirAll(FontDir, 0755); err != nil {
+		panic(err)
+	}
+	if err := textproc.AddFontDir(FontDir); err != nil {
+		panic(err)
+	}
 }
 
 func main() {
@@ -247,6 +989,7 @@ func main() {
 
 	appdir := GetAppDir()
 	SetPaths(path.Join(appdir, ".."))
+	SetupFonts()
 
 	r := MakeRouter()
 	http.Handle("/", r)
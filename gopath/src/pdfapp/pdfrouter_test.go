@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"io/ioutil"
 	"local/document"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path"
 	"runtime"
 	"testing"
+	"textproc"
 )
 
 func do_request(t *testing.T, req *http.Request, expStatus int) []byte {
@@ -99,4 +103,154 @@ func TestRouter(t *testing.T) {
 			t.Errorf("Returned document had wrong fontsize %s", doc2.FontSize)
 		}
 	}
+
+	// Test getting the document as PostScript and pages as images
+	{
+		body := test_get(t, fmt.Sprintf("%s/png/%s/1/?dpi=150", base, id), http.StatusOK)
+		if !bytes.HasPrefix(body, []byte("\x89PNG")) {
+			t.Errorf("Returned page was not a PNG image")
+		}
+		body = test_get(t, fmt.Sprintf("%s/svg/%s/1/", base, id), http.StatusOK)
+		if !bytes.Contains(body, []byte("<svg")) {
+			t.Errorf("Returned page was not an SVG image")
+		}
+		body = test_get(t, fmt.Sprintf("%s/ps/%s/", base, id), http.StatusOK)
+		if !bytes.HasPrefix(body, []byte("%!PS-Adobe")) {
+			t.Errorf("Returned document was not PostScript")
+		}
+		body = test_get(t, fmt.Sprintf("%s/eps/%s/1/", base, id), http.StatusOK)
+		if !bytes.Contains(body, []byte("EPSF")) {
+			t.Errorf("Returned page was not EPS")
+		}
+		test_get(t, fmt.Sprintf("%s/svg/%s/2/", base, id), http.StatusNotFound)
+		test_get(t, fmt.Sprintf("%s/png/%s/1/?dpi=0", base, id), http.StatusBadRequest)
+	}
+
+	// Test that breaking the page twice leaves an empty page
+	{
+		doc := document.DefaultDocument()
+		doc.Text = "One\n\n\\newpage\n\\newpage\n\nThree"
+		jsonRep, _ := json.Marshal(doc)
+		req, _ := http.NewRequest("POST", base+"/document/", bytes.NewReader(jsonRep))
+		var doc2 document.Document
+		if err := json.Unmarshal(do_request(t, req, http.StatusOK), &doc2); err != nil {
+			t.Errorf("Could not unmarshall body")
+		}
+		test_get(t, fmt.Sprintf("%s/svg/%s/3/", base, doc2.Id), http.StatusOK)
+		test_get(t, fmt.Sprintf("%s/svg/%s/4/", base, doc2.Id), http.StatusNotFound)
+	}
+
+	// Test the font catalog
+	{
+		body := test_get(t, base+"/fonts/", http.StatusOK)
+		var families []textproc.FontFamily
+		if err := json.Unmarshal(body, &families); err != nil {
+			t.Errorf("Could not unmarshall font families")
+		}
+		if len(families) > 0 {
+			test_get(t, base+"/fonts/"+url.PathEscape(families[0].Name)+"/", http.StatusOK)
+		}
+		test_get(t, base+"/fonts/No%20Such%20Family/", http.StatusNotFound)
+		req, _ := http.NewRequest("POST", base+"/font/", bytes.NewReader([]byte("wOFF not supported")))
+		do_request(t, req, http.StatusUnsupportedMediaType)
+	}
+
+	// Test uploading and getting an image
+	{
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)))
+		data := buf.Bytes()
+		req, err := http.NewRequest("POST", base+"/image/", bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("Could not create request")
+		}
+		body := do_request(t, req, http.StatusOK)
+		var reply map[string]string
+		if err := json.Unmarshal(body, &reply); err != nil || reply["id"] == "" {
+			t.Errorf("Could not unmarshall image id from %q", body)
+		}
+		body = test_get(t, fmt.Sprintf("%s/image/%s/", base, reply["id"]), http.StatusOK)
+		if !bytes.Equal(body, data) {
+			t.Errorf("Returned image was %q", body)
+		}
+		req, _ = http.NewRequest("POST", base+"/image/", bytes.NewReader([]byte("GIF89a")))
+		do_request(t, req, http.StatusUnsupportedMediaType)
+		req, _ = http.NewRequest("POST", base+"/image/", bytes.NewReader([]byte("\x89PNG\r\n\x1a\n not really a PNG")))
+		do_request(t, req, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	type data struct {
+		Title string
+		Value string
+	}
+	var testData []data = []data{
+		data{"", `inline; filename="document.pdf"`},
+		data{"  A Tale of Two Cities ", `inline; filename="A_Tale_of_Two_Cities.pdf"`},
+		data{`"Quoted"/path\name`, `inline; filename="Quotedpathname.pdf"`},
+		data{"Café", `inline; filename="Caf_.pdf"; filename*=UTF-8''Caf%C3%A9.pdf`},
+	}
+	for _, d := range testData {
+		if value := contentDisposition(d.Title, ".pdf"); value != d.Value {
+			t.Errorf("contentDisposition(%q) was %q, not %q", d.Title, value, d.Value)
+		}
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	doc := document.DefaultDocument()
+	stamp := document.DefaultWatermark("CONFIDENTIAL")
+	stamp.FontSize = document.Length{}
+	stamp.Pages = "2-3, 5-"
+	stamp.Over = true
+	stamp.Placement = document.WatermarkTop
+	doc.Watermarks = []document.Watermark{document.DefaultWatermark("DRAFT"), stamp}
+	marks := watermarks(doc)
+	if len(marks) != 2 {
+		t.Fatalf("got %d watermarks", len(marks))
+	}
+	if marks[0].Text != "DRAFT" || marks[0].Fontsize != 72 || marks[0].Angle != 45 ||
+		marks[0].Pages != nil || marks[0].Center != nil {
+		t.Errorf("first watermark is %+v", marks[0])
+	}
+	if marks[1].Fontsize != document.DefaultWatermarkSize || !marks[1].Over || marks[1].Pages == nil ||
+		marks[1].Center == nil {
+		t.Fatalf("second watermark is %+v", marks[1])
+	}
+	for page, on := range map[int]bool{1: false, 2: true, 3: true, 4: false, 5: true, 100: true} {
+		if marks[1].Pages(page) != on {
+			t.Errorf("second watermark on page %d is %v", page, !on)
+		}
+	}
+	if x, y := marks[1].Center(612, 792); x != 306 || y != doc.TopMargin.Points()/2 {
+		t.Errorf("second watermark is centered at %g, %g", x, y)
+	}
+}
+
+func TestRightToLeftDocument(t *testing.T) {
+	doc := document.DefaultDocument()
+	doc.LeftMargin = document.LengthFromPoints(36)
+	doc.RightMargin = document.LengthFromPoints(72)
+	doc.Header = document.RunningHead{Left: "{title}", Center: "{date}", Right: "{page}"}
+	type data struct {
+		Direction   string
+		LeftMargin  float64
+		RightMargin float64
+		Header      textproc.RunningHead
+	}
+	var testData []data = []data{data{"", 36, 72, textproc.RunningHead{Left: "{title}", Center: "{date}", Right: "{page}"}},
+		data{document.LeftToRight, 36, 72, textproc.RunningHead{Left: "{title}", Center: "{date}", Right: "{page}"}},
+		data{document.RightToLeft, 72, 36, textproc.RunningHead{Left: "{page}", Center: "{date}", Right: "{title}"}},
+	}
+	for _, d := range testData {
+		doc.Direction = d.Direction
+		props := typesettingProps(doc)
+		if props.LeftMargin != d.LeftMargin || props.RightMargin != d.RightMargin {
+			t.Errorf("%q: margins are %g and %g", d.Direction, props.LeftMargin, props.RightMargin)
+		}
+		if heads := pageHeads(doc); heads.Header != d.Header {
+			t.Errorf("%q: header is %+v", d.Direction, heads.Header)
+		}
+	}
 }
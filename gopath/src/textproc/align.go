@@ -0,0 +1,85 @@
+package textproc
+
+// Alignments of the lines of paragraphs. The lines of a justified paragraph fill the
+// text width, except the last, which is set by the last line alignment, unless the
+// alignment is AlignJustifyAll. AlignStart, only for last lines, sets a line at the
+// side its paragraph starts from: the left, or the right for right-to-left text.
+const (
+	AlignJustify    = "justify"
+	AlignLeft       = "left"
+	AlignRight      = "right"
+	AlignCenter     = "center"
+	AlignJustifyAll = "justify-all-lines"
+	AlignStart      = "start"
+)
+
+// justified returns true if the lines of a paragraph, except perhaps the last, fill its width.
+func (props TypesettingProps) justified() bool {
+	return props.Alignment == "" || props.Alignment == AlignJustify || props.Alignment == AlignJustifyAll
+}
+
+// justifyLastLine returns true if the last line of a paragraph fills its width too.
+func (props TypesettingProps) justifyLastLine() bool {
+	return props.Alignment == AlignJustifyAll || (props.justified() && props.LastLine == AlignJustify)
+}
+
+// lineAlignment returns the side a line is set at, AlignLeft, AlignRight or AlignCenter.
+// A justified line, which fills the width, is set at its start.
+func (props TypesettingProps) lineAlignment(last bool, rtl bool) string {
+	align := props.Alignment
+	switch {
+	case props.justifyLastLine() || (props.justified() && !last):
+		align = AlignStart
+	case props.justified():
+		align = props.LastLine
+	}
+	if align == "" || align == AlignStart {
+		if rtl {
+			return AlignRight
+		}
+		return AlignLeft
+	}
+	return align
+}
+
+// lineOffset returns how far a line of width lineWidth starts from the left of the
+// width of its paragraph, when set at the side given by align. indent is left at the
+// side the paragraph starts from, the right if rtl is true.
+func lineOffset(align string, rtl bool, width, lineWidth, indent float64) float64 {
+	left, right := 0.0, width
+	if rtl {
+		right -= indent
+	} else {
+		left += indent
+	}
+	switch align {
+	case AlignRight:
+		return right - lineWidth
+	case AlignCenter:
+		return (left + right - lineWidth) / 2
+	}
+	return left
+}
+
+// fillRatio returns the adjustment ratio that makes the items of a line, but for the
+// glue that fills the last line of a paragraph, as wide as width.
+func fillRatio(items []breakItem, width float64) float64 {
+	natural, stretch, shrink := 0.0, 0.0, 0.0
+	for _, item := range items {
+		switch {
+		case item.kind == boxItem:
+			natural += item.width
+		case item.kind == glueItem && item.stretch < fillStretch:
+			natural += item.width
+			stretch += item.stretch
+			shrink += item.shrink
+		}
+	}
+	switch {
+	case natural < width && stretch > 0:
+		return (width - natural) / stretch
+	case natural > width && shrink > 0:
+		return (width - natural) / shrink
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestLineAlignment(t *testing.T) {
+	type data struct {
+		Alignment, LastLine string
+		Last, RTL           bool
+		Align               string
+	}
+	var testData []data = []data{data{"", "", false, false, AlignLeft},
+		data{"", "", true, false, AlignLeft},
+		data{"", "", true, true, AlignRight},
+		data{AlignJustify, AlignCenter, false, false, AlignLeft},
+		data{AlignJustify, AlignCenter, true, false, AlignCenter},
+		data{AlignJustify, AlignRight, true, true, AlignRight},
+		data{AlignJustify, AlignJustify, true, true, AlignRight},
+		data{AlignJustifyAll, AlignCenter, true, false, AlignLeft},
+		data{AlignCenter, AlignLeft, false, false, AlignCenter},
+		data{AlignRight, "", true, false, AlignRight},
+		data{AlignLeft, "", false, true, AlignLeft},
+	}
+	for _, d := range testData {
+		props := TypesettingProps{Alignment: d.Alignment, LastLine: d.LastLine}
+		if align := props.lineAlignment(d.Last, d.RTL); align != d.Align {
+			t.Errorf("lineAlignment(%v, %v) for %q, %q was %q", d.Last, d.RTL, d.Alignment, d.LastLine, align)
+		}
+	}
+}
+
+func TestLineOffset(t *testing.T) {
+	type data struct {
+		Align     string
+		RTL       bool
+		LineWidth float64
+		Indent    float64
+		Offset    float64
+	}
+	var testData []data = []data{data{AlignLeft, false, 200, 0, 0},
+		data{AlignLeft, false, 200, 20, 20},
+		data{AlignLeft, true, 200, 20, 0},
+		data{AlignRight, false, 200, 20, 100},
+		data{AlignRight, true, 200, 20, 80},
+		data{AlignCenter, false, 200, 0, 50},
+		data{AlignCenter, false, 200, 20, 60},
+		data{AlignCenter, true, 200, 20, 40},
+	}
+	for _, d := range testData {
+		if offset := lineOffset(d.Align, d.RTL, 300, d.LineWidth, d.Indent); offset != d.Offset {
+			t.Errorf("lineOffset(%v) was %g", d, offset)
+		}
+	}
+}
+
+func TestFillRatio(t *testing.T) {
+	box := breakItem{kind: boxItem, width: 40}
+	glue := breakItem{kind: glueItem, width: 10, stretch: 5, shrink: 3}
+	fill := breakItem{kind: glueItem, stretch: fillStretch}
+	items := []breakItem{box, glue, box, glue, box, fill}
+	type data struct {
+		Width float64
+		Ratio float64
+	}
+	var testData []data = []data{data{140, 0},
+		data{150, 1},
+		data{160, 2},
+		data{134, -1},
+	}
+	for _, d := range testData {
+		if ratio := fillRatio(items, d.Width); ratio != d.Ratio {
+			t.Errorf("fillRatio for width %g was %g", d.Width, ratio)
+		}
+	}
+	if ratio := fillRatio([]breakItem{box, fill}, 100); ratio != 0 {
+		t.Errorf("fillRatio for a single box was %g", ratio)
+	}
+}
@@ -1,6 +1,10 @@
+#include <stdlib.h>
+#include <string.h>
 #include <pango/pango.h>
 #include <cairo.h>
 #include <cairo-pdf.h>
+#include <cairo-ps.h>
+#include <cairo-svg.h>
 
 extern cairo_status_t GoWriteToStream(void *closure, unsigned char *data, unsigned int length);
 
@@ -9,6 +13,11 @@ PangoFontFamily *indexFamily(PangoFontFamily **array, int i)
     return array[i]; 
 }
 
+PangoFontFace *indexFace(PangoFontFace **array, int i)
+{
+    return array[i];
+}
+
 cairo_surface_t *gocairo_pdf_surface_create_for_stream (
         void *closure, 
         double width_in_points, 
@@ -17,3 +26,80 @@ cairo_surface_t *gocairo_pdf_surface_create_for_stream (
 	typedef cairo_status_t (*WriteFn)(void *, const unsigned char *, unsigned int);
 	return cairo_pdf_surface_create_for_stream((WriteFn)&GoWriteToStream, closure, width_in_points, height_in_points);
 }
+
+// Copy the attributes of list that apply to the byte range [start, end) into dest,
+// moved so that they apply from offset.
+void gopango_attr_list_copy_range(PangoAttrList *dest, PangoAttrList *list, int start, int end, int offset)
+{
+    PangoAttrIterator *iter = pango_attr_list_get_iterator(list);
+    do {
+        int s, e;
+        pango_attr_iterator_range(iter, &s, &e);
+        if (e > start && s < end) {
+            GSList *attrs = pango_attr_iterator_get_attrs(iter);
+            GSList *l;
+            for (l = attrs; l != NULL; l = l->next) {
+                PangoAttribute *attr = l->data;
+                attr->start_index = (s > start ? s : start) - start + offset;
+                attr->end_index = (e < end ? e : end) - start + offset;
+                pango_attr_list_insert(dest, attr);
+            }
+            g_slist_free(attrs);
+        }
+    } while (pango_attr_iterator_next(iter));
+    pango_attr_iterator_destroy(iter);
+}
+
+// gocairo_png_source is image data being read by cairo.
+typedef struct {
+    const unsigned char *data;
+    unsigned int length;
+    unsigned int offset;
+} gocairo_png_source;
+
+static cairo_status_t gocairo_read_png(void *closure, unsigned char *data, unsigned int length)
+{
+    gocairo_png_source *source = closure;
+    if (source->length - source->offset < length) {
+        return CAIRO_STATUS_READ_ERROR;
+    }
+    memcpy(data, source->data + source->offset, length);
+    source->offset += length;
+    return CAIRO_STATUS_SUCCESS;
+}
+
+// Create an image surface from PNG data in memory.
+cairo_surface_t *gocairo_image_surface_create_from_png_data(const unsigned char *data, unsigned int length)
+{
+    gocairo_png_source source = { data, length, 0 };
+    return cairo_image_surface_create_from_png_stream(gocairo_read_png, &source);
+}
+
+// Attach a copy of JPEG data to a surface, for backends that can embed it.
+cairo_status_t gocairo_surface_set_jpeg_data(cairo_surface_t *surface, const unsigned char *data, unsigned long length)
+{
+    unsigned char *copy = malloc(length);
+    if (copy == NULL) {
+        return CAIRO_STATUS_NO_MEMORY;
+    }
+    memcpy(copy, data, length);
+    return cairo_surface_set_mime_data(surface, CAIRO_MIME_TYPE_JPEG, copy, length, free, copy);
+}
+
+cairo_surface_t *gocairo_svg_surface_create_for_stream(void *closure, double width_in_points, double height_in_points)
+{
+	typedef cairo_status_t (*WriteFn)(void *, const unsigned char *, unsigned int);
+	return cairo_svg_surface_create_for_stream((WriteFn)&GoWriteToStream, closure, width_in_points, height_in_points);
+}
+
+cairo_status_t gocairo_surface_write_to_png_stream(cairo_surface_t *surface, void *closure)
+{
+	typedef cairo_status_t (*WriteFn)(void *, const unsigned char *, unsigned int);
+	return cairo_surface_write_to_png_stream(surface, (WriteFn)&GoWriteToStream, closure);
+}
+
+cairo_surface_t *gocairo_ps_surface_create_for_stream(void *closure, double width_in_points, double height_in_points)
+{
+	typedef cairo_status_t (*WriteFn)(void *, const unsigned char *, unsigned int);
+	return cairo_ps_surface_create_for_stream((WriteFn)&GoWriteToStream, closure, width_in_points, height_in_points);
+}
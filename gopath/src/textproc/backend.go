@@ -21,7 +21,6 @@ cairo_surface_t *	gocairo_pdf_surface_create_for_stream (
 import "C"
 
 import (
-	"fmt"
 	"io"
 	"unsafe"
 )
@@ -32,7 +31,9 @@ func ListFontFamilies() []string {
 	var families **C.PangoFontFamily
 	var nfam C.int
 	var fontmap *C.PangoFontMap
-	fontmap = C.pango_cairo_font_map_get_default()
+	// A font map of its own, as the one text is laid out with may be in use.
+	fontmap = C.pango_cairo_font_map_new()
+	defer C.g_object_unref(C.gpointer(fontmap))
 	C.pango_font_map_list_families(fontmap, &families, &nfam)
 	for i := 0; i < int(nfam); i++ {
 		family := C.indexFamily(families, C.int(i))
@@ -47,13 +48,22 @@ func ListFontFamilies() []string {
 func GoWriteToStream(closure unsafe.Pointer, data *C.uchar, length C.uint) C.cairo_status_t {
 	stream := *(*io.Writer)(closure)
 	bytes := C.GoBytes(unsafe.Pointer(data), C.int(length))
-	stream.Write(bytes)
+	if _, err := stream.Write(bytes); err != nil {
+		return C.CAIRO_STATUS_WRITE_ERROR
+	}
 	return C.cairo_status_t(0)
 }
 
 // All values are in POINTS
 type TypesettingProps struct {
-	Fontname     string
+	Fontname string
+	// FontWeight is from 100 to 1000, or 0 for normal. FontStyle, FontStretch and
+	// FontVariant are named as in CSS, such as "italic", "condensed" and "small-caps",
+	// or empty for normal.
+	FontWeight   int
+	FontStyle    string
+	FontStretch  string
+	FontVariant  string
 	Fontsize     float64
 	Baselineskip float64
 	TopMargin    float64
@@ -62,63 +72,642 @@ type TypesettingProps struct {
 	RightMargin  float64
 	PageWidth    float64
 	PageHeight   float64
+	// FontFeatures are OpenType feature settings, such as "onum=1" or "smcp", and
+	// LetterSpacing is extra space between letters.
+	FontFeatures  []string
+	LetterSpacing float64
+	// Language is the BCP 47 tag of the language of text, such as "en-US", and
+	// Direction is LeftToRight, RightToLeft, or AutoDirection if empty. A right-to-left
+	// paragraph is indented and aligned from the right, and has its label at the right.
+	Language  string
+	Direction string
+	// Indent is the indentation of the first line of a paragraph.
+	Indent float64
+	// ParSkip is the extra space between paragraphs.
+	ParSkip float64
+	// Alignment is how the lines of a paragraph are set, such as AlignJustify, and
+	// LastLine how the last line of a justified paragraph is, such as AlignStart.
+	// An empty Alignment is AlignJustify, and an empty LastLine AlignStart.
+	Alignment string
+	LastLine  string
+	// Markup is true if text is in Pango markup rather than plain text.
+	Markup bool
+	// LeftIndent is the indentation of a whole paragraph from the left margin.
+	LeftIndent float64
+	// Label is set in the left indent, on the first line of a paragraph,
+	// like the bullet of a list item.
+	Label string
+	// OptimalBreaking chooses the Knuth-Plass line breaker over Pango's greedy one.
+	// The Knuth-Plass breaker has its own parameters, named as in TeX.
+	OptimalBreaking bool
+	Tolerance       float64
+	Looseness       int
+	HyphenPenalty   float64
+	ExHyphenPenalty float64
+	// Notes are the footnotes of a paragraph, whose references are marked in its text by NoteMark.
+	Notes []Footnote
+	// Links are the links of a paragraph, whose texts are marked in its text by
+	// LinkStart and LinkEnd. Anchor names the paragraph as the destination of links.
+	Links  []Link
+	Anchor string
+	// outlineTitle and outlineLevel are the entry of a heading in the outline.
+	outlineTitle string
+	outlineLevel int
+	// textFontsize and textBaselineskip are the sizes of body text, if a paragraph is
+	// scaled from them, as headings are. Its notes are set at the size of body text.
+	textFontsize     float64
+	textBaselineskip float64
+	// Columns is the number of columns of text, separated by ColumnSep.
+	// ColumnRule draws a rule in the middle of the space between columns.
+	Columns    int
+	ColumnSep  float64
+	ColumnRule bool
+	// TextColor is the color of text, RuleColor of rules and ColumnRuleColor of
+	// the rules between columns.
+	TextColor       Color
+	RuleColor       Color
+	ColumnRuleColor Color
+	// Widows and Orphans are the fewest lines of a paragraph to leave at the top and
+	// at the bottom of a column. Breaking a paragraph with fewer costs WidowPenalty
+	// or OrphanPenalty, and is never done if that is infinitePenalty (10000) or more.
+	Widows        int
+	Orphans       int
+	WidowPenalty  float64
+	OrphanPenalty float64
+	// BaselineGrid puts each paragraph, rule and image on the next baseline of a grid
+	// of lines Baselineskip apart, so that lines line up across columns and pages.
+	// Lines of headings are not on the grid, but what follows them is.
+	BaselineGrid bool
+}
+
+// FirstBaseline returns the position of the first baseline on a page.
+func (props TypesettingProps) FirstBaseline() float64 {
+	return props.TopMargin + props.Fontsize
+}
+
+// columnCount returns the number of columns, which is at least one.
+func (props TypesettingProps) columnCount() int {
+	if props.Columns < 1 {
+		return 1
+	}
+	return props.Columns
+}
+
+// ColumnWidth returns the width of a column of text.
+func (props TypesettingProps) ColumnWidth() float64 {
+	n := props.columnCount()
+	return (props.PageWidth - props.LeftMargin - props.RightMargin - float64(n-1)*props.ColumnSep) / float64(n)
+}
+
+// columnStride returns the distance from the left of one column to the left of the next.
+func (props TypesettingProps) columnStride() float64 {
+	return props.ColumnWidth() + props.ColumnSep
+}
+
+// TextWidth returns the width of the lines of a paragraph.
+func (props TypesettingProps) TextWidth() float64 {
+	return props.ColumnWidth() - props.LeftIndent
+}
+
+// LastBaseline returns the lowest position a baseline may have on a page.
+func (props TypesettingProps) LastBaseline() float64 {
+	return props.PageHeight - props.BottomMargin
 }
 
 type TextObject interface {
-	WriteAt(text string, props TypesettingProps, baseline float64) error
+	WriteAt(text string, props TypesettingProps, x float64, y float64) error
 	Close()
 }
 
-type PDFStreamTextObject struct {
+// StreamTextObject lays out text in pages, and writes them to a stream.
+// Only one is open at a time: making another waits until it is closed. It should
+// write to a buffer rather than to a network connection, so that a slow reader does
+// not hold up the others.
+type StreamTextObject struct {
+	// surface and context are what text is laid out with.
 	surface *C.cairo_surface_t
 	context *C.cairo_t
+	// writer makes the surfaces pages are drawn on, and canvas is the context
+	// for drawing the current page, or nil if the page is not being drawn.
+	writer pageWriter
+	canvas *C.cairo_t
+	// y is the position of the next baseline on the current page.
+	y float64
+	// pages is the number of pages that have been started.
+	pages int
+	// blank is true if nothing has been drawn on the current page, and broken
+	// is true if the page was started by NewPage.
+	blank  bool
+	broken bool
+	// width and height are the size of the current page.
+	width  float64
+	height float64
+	// sizes are the sizes of the pages before the current one.
+	sizes []PageSize
+	// heads are the header and footer, if there are any, set in headProps.
+	heads     *PageHeads
+	headProps TypesettingProps
+	// column is the current column, counting from 0.
+	column int
+	// items are what has been set on the current page, in order, and props
+	// are the properties of the last of them.
+	items []pageItem
+	props TypesettingProps
+	// done are the paragraphs that have been set, which are freed with their page.
+	done []paragraph
+	// notes are the footnotes of the current page, and noteSpace is the space
+	// taken by those in the current column.
+	notes     []placedNote
+	noteSpace float64
+	// surfaces are the images of the current page.
+	surfaces []*C.cairo_surface_t
+	// outline are the last outline entries of each level up to the last one added.
+	outline []outlineEntry
+	// pageColor is the color of the background of the pages, or nil if they are left blank.
+	pageColor *Color
+	// watermarks are stamped on the pages, with the font and margins of watermarkProps.
+	watermarks     []Watermark
+	watermarkProps TypesettingProps
+	// gridProps are the properties of the baseline grid drawn on the pages, or nil if it is not.
+	gridProps *TypesettingProps
+}
+
+// pageItem is something set on the current page. Items are drawn when the page is
+// finished, so that they can be moved between columns.
+type pageItem struct {
+	column int
+	// y is the baseline of the item.
+	y float64
+	// draw draws the item, moved right by dx, on the baseline y.
+	draw func(dx float64, y float64)
+}
+
+// place adds an item to the current column.
+func (t *StreamTextObject) place(y float64, props TypesettingProps, draw func(dx float64, y float64)) {
+	t.items = append(t.items, pageItem{column: t.column, y: y, draw: draw})
+	t.props = props
+	t.blank = false
+}
+
+// columnEmpty returns true if nothing has been set in the current column.
+func (t *StreamTextObject) columnEmpty() bool {
+	return len(t.items) == 0 || t.items[len(t.items)-1].column != t.column
+}
+
+// nextColumn moves to the top of the next column, or of a new page after the last column.
+func (t *StreamTextObject) nextColumn(props TypesettingProps) {
+	if t.column+1 < props.columnCount() {
+		t.column++
+		t.noteSpace = 0
+	} else {
+		t.newPage()
+	}
+	t.y = props.FirstBaseline()
+}
+
+// finishPage draws the items, heads and watermarks of the current page, and frees
+// the paragraphs that have been set.
+func (t *StreamTextObject) finishPage() {
+	props := t.props
+	t.canvas = t.writer.beginPage(t.pages, t.width, t.height)
+	if t.canvas != nil {
+		t.paintPage()
+		t.writeWatermarks(false)
+		t.writeGrid()
+		for _, item := range t.items {
+			item.draw(props.columnOffset(item.column), item.y)
+		}
+		if props.ColumnRule && len(t.items) > 0 {
+			t.writeColumnRules(props)
+		}
+		t.writeNotes()
+		t.writeHeads()
+		t.writeWatermarks(true)
+		t.writer.endPage()
+		t.canvas = nil
+	}
+	t.freeNotes()
+	for _, p := range t.done {
+		p.free()
+	}
+	for _, surface := range t.surfaces {
+		C.cairo_surface_destroy(surface)
+	}
+	t.items = nil
+	t.done = nil
+	t.surfaces = nil
+}
+
+// writeColumnRules draws a rule before each column that has anything in it,
+// from the top margin to just below the lowest baseline.
+func (t *StreamTextObject) writeColumnRules(props TypesettingProps) {
+	bottom := 0.0
+	for _, item := range t.items {
+		if item.y > bottom {
+			bottom = item.y
+		}
+	}
+	bottom += props.Fontsize / 4
+	last := t.items[len(t.items)-1].column
+	t.setColor(props.ColumnRuleColor)
+	C.cairo_set_line_width(t.canvas, 0.5)
+	for c := 1; c <= last; c++ {
+		x := props.LeftMargin + (props.columnOffset(c-1)+props.columnOffset(c)+props.ColumnWidth())/2
+		C.cairo_move_to(t.canvas, C.double(x), C.double(props.TopMargin))
+		C.cairo_line_to(t.canvas, C.double(x), C.double(bottom))
+	}
+	C.cairo_stroke(t.canvas)
 }
 
-func (t *PDFStreamTextObject) WriteAt(text string, props TypesettingProps, x float64, y float64) error {
+// balanceColumns moves the lines of the current page between its columns,
+// so that the columns are as nearly equal in length as possible.
+func (t *StreamTextObject) balanceColumns() {
+	n := t.props.columnCount()
+	if n < 2 || len(t.items) == 0 || len(t.notes) > 0 {
+		return
+	}
+	// offsets are the positions of the items in one long column.
+	offsets := make([]float64, len(t.items))
+	top := t.items[0].y
+	base := 0.0
+	for i, item := range t.items {
+		if i > 0 && item.column != t.items[i-1].column {
+			base = offsets[i-1] + t.props.Baselineskip - (item.y - top)
+		}
+		offsets[i] = base + item.y - top
+	}
+	// fit returns the columns of the items in columns no longer than height,
+	// or nil if they need more than n columns.
+	fit := func(height float64) []int {
+		columns := make([]int, len(offsets))
+		column, start := 0, 0.0
+		for i, offset := range offsets {
+			// Items on the same line stay together.
+			sameLine := i > 0 && offset == offsets[i-1]
+			if offset-start > height && !sameLine {
+				column++
+				start = t.props.gridFloor(offset)
+				if column == n {
+					return nil
+				}
+			}
+			columns[i] = column
+		}
+		return columns
+	}
+	limit := t.props.LastBaseline() - top
+	height := offsets[len(offsets)-1] / float64(n)
+	columns := fit(height)
+	for columns == nil && height < limit {
+		height++
+		columns = fit(height)
+	}
+	if columns == nil {
+		return
+	}
+	start := 0.0
+	for i := range t.items {
+		if i == 0 || columns[i] != columns[i-1] {
+			start = t.props.gridFloor(offsets[i])
+		}
+		t.items[i].column = columns[i]
+		t.items[i].y = top + offsets[i] - start
+	}
+}
+
+// newPage finishes the current page and starts a new one.
+func (t *StreamTextObject) newPage() {
+	t.finishPage()
+	t.sizes = append(t.sizes, PageSize{t.width, t.height})
+	t.pages++
+	t.blank = true
+	t.broken = false
+	t.column = 0
+	t.noteSpace = 0
+}
+
+// NewPage starts a new page of the given size. If the current page is still blank,
+// it is resized instead, unless it was itself started by NewPage, so that breaking
+// the page twice leaves an empty page.
+func (t *StreamTextObject) NewPage(width, height float64) {
+	if !t.blank || t.broken {
+		t.newPage()
+	}
+	t.width = width
+	t.height = height
+	t.broken = true
+}
+
+// WriteParagraph lays out a paragraph below the last one written,
+// or at the top of the page if the page is blank.
+func (t *StreamTextObject) WriteParagraph(text string, props TypesettingProps) error {
+	if t.blank {
+		t.y = props.FirstBaseline()
+	} else {
+		t.y = props.onGrid(t.y + props.ParSkip)
+	}
+	x := props.LeftMargin + props.LeftIndent
+	if t.rightToLeft(text, props) {
+		// The indent of a right-to-left paragraph is at its right.
+		x = props.LeftMargin
+	}
+	return t.WriteAt(text, props, x, t.y)
+}
+
+// headingScales are the font size scales of headings, by level.
+var headingScales = []float64{1.728, 1.44, 1.2}
+
+// WriteHeading lays out a heading of the given level, starting at 1, in bold.
+// The font size and baseline skip of the top levels are scaled up from those in props,
+// and a blank line is left above the heading. If props has an Anchor, the heading
+// is also added to the outline of PDF output.
+func (t *StreamTextObject) WriteHeading(text string, level int, props TypesettingProps) error {
+	hprops := props
+	if props.Anchor != "" {
+		hprops.outlineTitle = plainText(text, props.Markup)
+		hprops.outlineLevel = level
+	}
+	if level >= 1 && level <= len(headingScales) {
+		hprops.textFontsize = props.Fontsize
+		hprops.textBaselineskip = props.Baselineskip
+		hprops.Fontsize *= headingScales[level-1]
+		hprops.Baselineskip *= headingScales[level-1]
+	}
+	hprops.ParSkip = props.Baselineskip
+	hprops.BaselineGrid = false
+	hprops.Indent = 0
+	hprops.LeftIndent = 0
+	hprops.Label = ""
+	if !props.Markup {
+		text = escapeMarkup(text)
+	}
+	hprops.Markup = true
+	return t.WriteParagraph("<b>"+text+"</b>", hprops)
+}
+
+// WriteRule draws a horizontal rule across the text block, taking the space of one line.
+func (t *StreamTextObject) WriteRule(props TypesettingProps) {
+	if t.blank {
+		t.y = props.FirstBaseline()
+	} else {
+		t.y = props.onGrid(t.y + props.ParSkip)
+	}
+	if t.y > t.textProps(props).LastBaseline() {
+		t.nextColumn(props)
+	}
+	x := props.LeftMargin + props.LeftIndent
+	width := props.TextWidth()
+	t.place(t.y, props, func(dx float64, y float64) {
+		// Put the rule at about the height of the middle of lowercase letters.
+		y -= props.Fontsize / 4
+		t.setColor(props.RuleColor)
+		C.cairo_set_line_width(t.canvas, 0.5)
+		C.cairo_move_to(t.canvas, C.double(x+dx), C.double(y))
+		C.cairo_line_to(t.canvas, C.double(x+dx+width), C.double(y))
+		C.cairo_stroke(t.canvas)
+	})
+	t.y += props.Baselineskip
+}
+
+// makeLayout returns a layout of text, wrapped to width, or unwrapped if width is negative.
+// The caller must release it with g_object_unref.
+func (t *StreamTextObject) makeLayout(text string, props TypesettingProps, width float64) *C.PangoLayout {
 	var layout *C.PangoLayout
 	var font_description *C.PangoFontDescription
 
-	font_description = C.pango_font_description_new()
-	cfontname := C.CString(props.Fontname)
-	defer C.free(unsafe.Pointer(cfontname))
-	C.pango_font_description_set_family(font_description, cfontname)
-	C.pango_font_description_set_weight(font_description, C.PANGO_WEIGHT_NORMAL)
-	C.pango_font_description_set_absolute_size(font_description, C.double(props.Fontsize)*C.PANGO_SCALE)
+	font_description = props.fontDescription()
 
-	layout = C.pango_cairo_create_layout(t.context)
+	fontmap := currentFontMap()
+	context := C.pango_font_map_create_context(fontmap)
+	C.g_object_unref(C.gpointer(fontmap))
+	C.pango_cairo_update_context(t.context, context)
+	props.setContext(context)
+	layout = C.pango_layout_new(context)
+	C.g_object_unref(C.gpointer(context))
+	if props.Direction == LeftToRight || props.Direction == RightToLeft {
+		C.pango_layout_set_auto_dir(layout, C.FALSE)
+	}
 	C.pango_layout_set_font_description(layout, font_description)
-	width := props.PageWidth - props.LeftMargin - props.RightMargin
-	fmt.Printf("width is %f\n", width)
-	C.pango_layout_set_width(layout, C.int(width*C.PANGO_SCALE))
-	C.pango_layout_set_justify(layout, C.TRUE)
+	C.pango_font_description_free(font_description)
+	if width >= 0 {
+		C.pango_layout_set_width(layout, C.int(width*C.PANGO_SCALE))
+	} else {
+		C.pango_layout_set_width(layout, -1)
+	}
 	ctext := C.CString(text)
 	defer C.free(unsafe.Pointer(ctext))
-	C.pango_layout_set_text(layout, ctext, -1)
+	if props.Markup {
+		C.pango_layout_set_markup(layout, ctext, -1)
+	} else {
+		C.pango_layout_set_text(layout, ctext, -1)
+	}
+	if len(props.FontFeatures) > 0 || props.LetterSpacing != 0 {
+		props.setAttributes(layout, C.pango_layout_get_attributes(layout))
+	}
+	return layout
+}
 
-	C.cairo_set_source_rgb(t.context, 0.0, 0.0, 0.0)
-	C.cairo_move_to(t.context, C.double(x), C.double(y))
-	skip := props.Baselineskip
-	nlines := int(C.pango_layout_get_line_count(layout))
-	for i := 0; i < nlines; i++ {
-		C.cairo_move_to(t.context, C.double(x), C.double(y+float64(i)*skip))
-		C.pango_cairo_show_layout_line(t.context, C.pango_layout_get_line(layout, C.int(i)))
+// writeLabel sets a label so that it ends a little before x, on the baseline y,
+// or for a right-to-left paragraph, so that it starts a little after x.
+func (t *StreamTextObject) writeLabel(label string, props TypesettingProps, x float64, y float64, rtl bool) {
+	lprops := props
+	lprops.Markup = false
+	layout := t.makeLayout(label, lprops, -1)
+	defer C.g_object_unref(C.gpointer(layout))
+	line := C.pango_layout_get_line(layout, 0)
+	var logical C.PangoRectangle
+	C.pango_layout_line_get_extents(line, nil, &logical)
+	width := float64(logical.width) / C.PANGO_SCALE
+	if rtl {
+		x += width + props.Fontsize
 	}
+	C.cairo_move_to(t.canvas, C.double(x-width-props.Fontsize/2), C.double(y))
+	C.pango_cairo_show_layout_line(t.canvas, line)
+}
 
-	C.g_object_unref(C.gpointer(layout))
-	C.pango_font_description_free(font_description)
+// paragraph is a paragraph that has been broken into lines.
+type paragraph interface {
+	lineCount() int
+	// lineOfIndex returns the line holding the byte at index in the paragraph's plain text.
+	lineOfIndex(index int) int
+	// drawLine draws line i on cr, with its start at x, on the baseline y.
+	drawLine(cr *C.cairo_t, i int, x float64, y float64)
+	// indexX returns the offset from the start of line i of the text at a byte index in it.
+	indexX(i int, index int) float64
+	// lineWidth returns the width of line i.
+	lineWidth(i int) float64
+	free()
+}
+
+// greedyParagraph is a paragraph broken into lines by Pango.
+type greedyParagraph struct {
+	layout *C.PangoLayout
+}
+
+func (p *greedyParagraph) lineCount() int {
+	return int(C.pango_layout_get_line_count(p.layout))
+}
+
+func (p *greedyParagraph) lineOfIndex(index int) int {
+	var line, x C.int
+	C.pango_layout_index_to_line_x(p.layout, C.int(index), C.FALSE, &line, &x)
+	return int(line)
+}
+
+func (p *greedyParagraph) drawLine(cr *C.cairo_t, i int, x float64, y float64) {
+	C.cairo_move_to(cr, C.double(x), C.double(y))
+	C.pango_cairo_show_layout_line(cr, C.pango_layout_get_line(p.layout, C.int(i)))
+}
+
+func (p *greedyParagraph) indexX(i int, index int) float64 {
+	var x C.int
+	C.pango_layout_line_index_to_x(C.pango_layout_get_line(p.layout, C.int(i)), C.int(index), C.FALSE, &x)
+	return float64(x) / C.PANGO_SCALE
+}
+
+func (p *greedyParagraph) lineWidth(i int) float64 {
+	var logical C.PangoRectangle
+	C.pango_layout_line_get_extents(C.pango_layout_get_line(p.layout, C.int(i)), nil, &logical)
+	return float64(logical.width) / C.PANGO_SCALE
+}
+
+func (p *greedyParagraph) free() {
+	C.g_object_unref(C.gpointer(p.layout))
+}
+
+// greedyParagraph breaks text into lines with Pango.
+func (t *StreamTextObject) greedyParagraph(text string, props TypesettingProps) paragraph {
+	layout := t.makeLayout(text, props, props.TextWidth())
+	C.pango_layout_set_indent(layout, C.int(props.Indent*C.PANGO_SCALE))
+	if props.justified() {
+		C.pango_layout_set_justify(layout, C.TRUE)
+	}
+	if props.justifyLastLine() {
+		C.pango_layout_set_justify_last_line(layout, C.TRUE)
+	}
+	return &greedyParagraph{layout: layout}
+}
+
+// breakParagraph breaks text into lines. If props asks for optimal breaking
+// but that fails, it falls back to greedy breaking, as it does for right-to-left
+// paragraphs, whose lines the optimal breaker cannot set.
+func (t *StreamTextObject) breakParagraph(text string, props TypesettingProps) paragraph {
+	if props.OptimalBreaking && !t.rightToLeft(text, props) {
+		if p := t.optimalParagraph(text, props); p != nil {
+			return p
+		}
+	}
+	return t.greedyParagraph(text, props)
+}
+
+// WriteAt lays out text with its first baseline at (x, y), in the current column.
+// Lines that would fall below the bottom margin are continued at the top of
+// the next column, or of a new page. The lines of a right-to-left paragraph
+// end at x plus the width of the text.
+func (t *StreamTextObject) WriteAt(text string, props TypesettingProps, x float64, y float64) error {
+	width := props.TextWidth()
+	rtl := t.rightToLeft(text, props)
+	marked := text
+	if len(props.Notes) > 0 {
+		if !props.Markup {
+			marked = escapeMarkup(text)
+		}
+		text = replaceNoteMarks(marked, props.Notes)
+		props.Markup = true
+	}
+	var links []linkRange
+	if len(props.Links) > 0 {
+		links, _ = linkRanges(text, props.Markup, props.Links)
+	}
+	text = linkMarkRemover.Replace(text)
+	par := t.breakParagraph(text, props)
+	var notes map[int][]Footnote
+	if len(props.Notes) > 0 {
+		notes = lineNotes(par, linkMarkRemover.Replace(marked), props.Notes)
+	}
+
+	t.y = y
+	skip := props.Baselineskip
+	nlines := par.lineCount()
+	for i := 0; i < nlines; {
+		k := columnBreak(i, nlines, linesThatFit(t.y, t.textProps(props)), t.columnEmpty(), props)
+		for end := i + k; i < end; i++ {
+			if t.y > t.textProps(props).LastBaseline() && !t.columnEmpty() {
+				break
+			}
+			if len(notes[i]) > 0 && !t.addNotes(notes[i], props) {
+				break
+			}
+			i := i
+			t.place(t.y, props, func(dx float64, y float64) {
+				t.setColor(props.TextColor)
+				indent := 0.0
+				if i == 0 {
+					indent = props.Indent
+				}
+				align := props.lineAlignment(i == nlines-1, rtl)
+				lineX := x + dx + lineOffset(align, rtl, width, par.lineWidth(i), indent)
+				if i == 0 && props.Label != "" {
+					if rtl {
+						t.writeLabel(props.Label, props, x+dx+width, y, true)
+					} else {
+						t.writeLabel(props.Label, props, x+dx, y, false)
+					}
+				}
+				if i == 0 && props.Anchor != "" {
+					if props.outlineLevel > 0 {
+						t.addOutline(props.outlineTitle, props.outlineLevel, props.Anchor)
+					}
+					t.beginDest(props.Anchor)
+					par.drawLine(t.canvas, i, lineX, y)
+					t.endDest()
+				} else {
+					par.drawLine(t.canvas, i, lineX, y)
+				}
+				t.writeLinks(par, i, links, props, lineX, y)
+			})
+			t.y += skip
+		}
+		if i < nlines {
+			t.nextColumn(props)
+		}
+	}
+	t.done = append(t.done, par)
 	return nil
 }
 
-func (t *PDFStreamTextObject) Close() {
+// Close finishes the last page, with its columns balanced, and the document.
+func (t *StreamTextObject) Close() {
+	defer renderMutex.Unlock()
+	t.balanceColumns()
+	t.finishPage()
+	t.writer.close()
 	C.cairo_destroy(t.context)
 	C.cairo_surface_destroy(t.surface)
 	t.context = nil
 	t.surface = nil
 }
 
-func MakePDFStreamTextObject(writer io.Writer, width, height float64) *PDFStreamTextObject {
-	var t PDFStreamTextObject
-	t.surface = C.gocairo_pdf_surface_create_for_stream(unsafe.Pointer(&writer), C.double(width), C.double(height))
+// makeStreamTextObject returns a text object that lays out text on a PDF surface
+// writing to layoutWriter, and draws its pages with writer. It waits until no other
+// text object is open, and the caller must close it.
+func makeStreamTextObject(layoutWriter io.Writer, writer pageWriter, width, height float64) *StreamTextObject {
+	renderMutex.Lock()
+	var t StreamTextObject
+	t.surface = C.gocairo_pdf_surface_create_for_stream(unsafe.Pointer(&layoutWriter), C.double(width), C.double(height))
 	t.context = C.cairo_create(t.surface)
+	t.writer = writer
+	t.pages = 1
+	t.blank = true
+	t.width = width
+	t.height = height
 	return &t
 }
+
+// MakePDFStreamTextObject returns a text object writing a PDF file of all its pages.
+func MakePDFStreamTextObject(writer io.Writer, width, height float64) *StreamTextObject {
+	pw := &pdfPageWriter{}
+	t := makeStreamTextObject(writer, pw, width, height)
+	pw.surface, pw.context = t.surface, t.context
+	return t
+}
@@ -0,0 +1,133 @@
+package textproc
+
+/*
+#cgo pkg-config: pango pangocairo
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+
+PangoFontFamily *indexFamily(PangoFontFamily **array, int i);
+*/
+import "C"
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FontFamily is an installed font family, with its faces.
+type FontFamily struct {
+	Name string
+	// Monospace is true if all the characters of the family are as wide,
+	// and Variable if it is a variable font.
+	Monospace bool
+	Variable  bool
+	Faces     []FontFace
+	// Weights are the weights of the faces, in order.
+	Weights []int
+}
+
+// FontCatalog lists the installed font families. It is loaded when it is first
+// used, and is only loaded again when it is refreshed.
+type FontCatalog struct {
+	mutex    sync.Mutex
+	loaded   bool
+	families []FontFamily
+	// index is the index of each family in families, by its name in lower case.
+	index map[string]int
+}
+
+// Fonts is the catalog of the fonts Pango can use.
+var Fonts = &FontCatalog{}
+
+// faceWeights returns the weights of faces, in order and without repeats.
+func faceWeights(faces []FontFace) []int {
+	var weights []int
+	seen := make(map[int]bool)
+	for _, face := range faces {
+		if !seen[face.Weight] {
+			seen[face.Weight] = true
+			weights = append(weights, face.Weight)
+		}
+	}
+	sort.Ints(weights)
+	return weights
+}
+
+// byName sorts font families by name.
+type byName []FontFamily
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Less(i, j int) bool { return f[i].Name < f[j].Name }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+// load lists the font families Pango can use, from a font map of its own, as
+// the one text is laid out with may be in use. The catalog must be locked.
+func (c *FontCatalog) load() {
+	var families **C.PangoFontFamily
+	var nfam C.int
+	fontmap := C.pango_cairo_font_map_new()
+	defer C.g_object_unref(C.gpointer(fontmap))
+	C.pango_font_map_list_families(fontmap, &families, &nfam)
+	c.families = nil
+	for i := 0; i < int(nfam); i++ {
+		family := C.indexFamily(families, C.int(i))
+		faces := familyFaces(family)
+		c.families = append(c.families, FontFamily{
+			Name:      C.GoString(C.pango_font_family_get_name(family)),
+			Monospace: C.pango_font_family_is_monospace(family) != C.FALSE,
+			Variable:  C.pango_font_family_is_variable(family) != C.FALSE,
+			Faces:     faces,
+			Weights:   faceWeights(faces),
+		})
+	}
+	C.g_free(C.gpointer(families))
+	sort.Sort(byName(c.families))
+	c.index = make(map[string]int)
+	for i, family := range c.families {
+		c.index[strings.ToLower(family.Name)] = i
+	}
+	c.loaded = true
+}
+
+// Refresh lists the installed font families again, for fonts that have been
+// added or removed.
+func (c *FontCatalog) Refresh() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.load()
+}
+
+// Families returns the installed font families, ordered by name.
+func (c *FontCatalog) Families() []FontFamily {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded {
+		c.load()
+	}
+	return c.families
+}
+
+// FamilyNames returns the names of the installed font families, in order.
+func (c *FontCatalog) FamilyNames() []string {
+	var names []string
+	for _, family := range c.Families() {
+		names = append(names, family.Name)
+	}
+	return names
+}
+
+// Family returns the installed font family of the given name, ignoring case.
+// ok is false if there is none.
+func (c *FontCatalog) Family(name string) (family FontFamily, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.loaded {
+		c.load()
+	}
+	i, ok := c.index[strings.ToLower(name)]
+	if !ok {
+		return family, false
+	}
+	return c.families[i], true
+}
@@ -0,0 +1,22 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestFaceWeights(t *testing.T) {
+	faces := []FontFace{FontFace{"Bold", 700, "normal", "normal"},
+		FontFace{"Regular", 400, "normal", "normal"},
+		FontFace{"Italic", 400, "italic", "normal"},
+		FontFace{"Light", 300, "normal", "normal"}}
+	weights := faceWeights(faces)
+	expected := []int{300, 400, 700}
+	if len(weights) != len(expected) {
+		t.Fatalf("faceWeights returned %v", weights)
+	}
+	for i, w := range expected {
+		if weights[i] != w {
+			t.Errorf("faceWeights returned %v", weights)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#include <cairo.h>
+*/
+import "C"
+
+// Color is a color, with red, green and blue components from 0 to 1.
+// The zero Color is black.
+type Color struct {
+	R, G, B float64
+}
+
+// setColor sets the color that the current page is drawn with.
+func (t *StreamTextObject) setColor(c Color) {
+	C.cairo_set_source_rgb(t.canvas, C.double(c.R), C.double(c.G), C.double(c.B))
+}
+
+// SetPageColor sets the color of the background of every page, which is otherwise left blank.
+func (t *StreamTextObject) SetPageColor(c Color) {
+	t.pageColor = &c
+}
+
+// paintPage paints the background of the current page, if it has a color.
+func (t *StreamTextObject) paintPage() {
+	if t.pageColor == nil {
+		return
+	}
+	t.setColor(*t.pageColor)
+	C.cairo_paint(t.canvas)
+}
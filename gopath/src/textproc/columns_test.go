@@ -0,0 +1,37 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestBalanceColumns(t *testing.T) {
+	props := TypesettingProps{Fontsize: 10, Baselineskip: 12, TopMargin: 50, BottomMargin: 50,
+		PageHeight: 400, PageWidth: 300, Columns: 2}
+	type data struct {
+		Lines   int
+		Columns []int
+	}
+	var testData []data = []data{data{1, []int{0}},
+		data{4, []int{0, 0, 1, 1}},
+		data{5, []int{0, 0, 0, 1, 1}},
+	}
+	for _, d := range testData {
+		obj := &StreamTextObject{props: props}
+		y := props.FirstBaseline()
+		for i := 0; i < d.Lines; i++ {
+			obj.items = append(obj.items, pageItem{y: y})
+			y += props.Baselineskip
+		}
+		obj.balanceColumns()
+		for i, item := range obj.items {
+			if item.column != d.Columns[i] {
+				t.Errorf("%d lines: line %d is in column %d, want %d", d.Lines, i, item.column, d.Columns[i])
+			}
+		}
+		for i, item := range obj.items {
+			if i > 0 && item.column != obj.items[i-1].column && item.y != props.FirstBaseline() {
+				t.Errorf("%d lines: column %d starts at %g", d.Lines, item.column, item.y)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package textproc
+
+/*
+#cgo pkg-config: pango
+#include <stdlib.h>
+#include <pango/pango.h>
+*/
+import "C"
+
+import (
+	"unicode"
+	"unsafe"
+)
+
+// Text directions. With AutoDirection, or no direction, each paragraph runs the way
+// of its first letter.
+const (
+	LeftToRight   = "ltr"
+	RightToLeft   = "rtl"
+	AutoDirection = "auto"
+)
+
+// rtlScripts are the scripts written from right to left.
+var rtlScripts = []*unicode.RangeTable{unicode.Hebrew, unicode.Arabic, unicode.Syriac,
+	unicode.Thaana, unicode.Nko, unicode.Samaritan, unicode.Mandaic}
+
+// firstStrongRTL returns true if the first letter of text, or the first mark that sets
+// a direction, is right to left. found is false if text has neither.
+func firstStrongRTL(text string) (rtl bool, found bool) {
+	for _, r := range text {
+		switch {
+		case r == '\u200f': // RIGHT-TO-LEFT MARK
+			return true, true
+		case r == '\u200e': // LEFT-TO-RIGHT MARK
+			return false, true
+		case unicode.IsLetter(r):
+			return unicode.In(r, rtlScripts...), true
+		}
+	}
+	return false, false
+}
+
+// rightToLeft returns true if plain text runs right to left in the given direction.
+func rightToLeft(text string, direction string) bool {
+	switch direction {
+	case RightToLeft:
+		return true
+	case LeftToRight:
+		return false
+	}
+	rtl, _ := firstStrongRTL(text)
+	return rtl
+}
+
+// rightToLeft returns true if a paragraph runs right to left, in the direction of props.
+func (t *StreamTextObject) rightToLeft(text string, props TypesettingProps) bool {
+	if props.Direction == LeftToRight || props.Direction == RightToLeft {
+		return props.Direction == RightToLeft
+	}
+	return rightToLeft(plainText(text, props.Markup), props.Direction)
+}
+
+// columnOffset returns the distance from the left margin to the left of a column.
+// The columns of a right-to-left document run from right to left.
+func (props TypesettingProps) columnOffset(column int) float64 {
+	if props.Direction == RightToLeft {
+		column = props.columnCount() - 1 - column
+	}
+	return float64(column) * props.columnStride()
+}
+
+// setContext sets the language and base direction of props on a Pango context.
+func (props TypesettingProps) setContext(context *C.PangoContext) {
+	if props.Language != "" {
+		clanguage := C.CString(props.Language)
+		defer C.free(unsafe.Pointer(clanguage))
+		C.pango_context_set_language(context, C.pango_language_from_string(clanguage))
+	}
+	switch props.Direction {
+	case RightToLeft:
+		C.pango_context_set_base_dir(context, C.PANGO_DIRECTION_RTL)
+	case LeftToRight:
+		C.pango_context_set_base_dir(context, C.PANGO_DIRECTION_LTR)
+	default:
+		C.pango_context_set_base_dir(context, C.PANGO_DIRECTION_WEAK_LTR)
+	}
+}
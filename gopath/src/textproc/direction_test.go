@@ -0,0 +1,81 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestRightToLeft(t *testing.T) {
+	type data struct {
+		Text      string
+		Direction string
+		RTL       bool
+	}
+	var testData []data = []data{data{"Plain English.", "", false},
+		data{"שלום עולם", "", true},
+		// Hebrew with an English word in it runs right to left, and the other way around.
+		data{"אני אוהב Go מאוד", AutoDirection, true},
+		data{"The word שלום means peace.", AutoDirection, false},
+		// Digits and punctuation do not set the direction.
+		data{"2024: مرحبا Hello", "", true},
+		data{"(1) Hello مرحبا", "", false},
+		data{"\u200fHello", "", true},
+		data{"\u200eשלום", "", false},
+		data{"123", "", false},
+		// An explicit direction is kept, whatever the text.
+		data{"Hello שלום", RightToLeft, true},
+		data{"שלום Hello", LeftToRight, false},
+	}
+	for _, d := range testData {
+		if rtl := rightToLeft(d.Text, d.Direction); rtl != d.RTL {
+			t.Errorf("rightToLeft(%q, %q) was %v", d.Text, d.Direction, rtl)
+		}
+	}
+}
+
+func TestColumnOffset(t *testing.T) {
+	props := TypesettingProps{LeftMargin: 50, RightMargin: 50, PageWidth: 375, Columns: 3, ColumnSep: 25}
+	type data struct {
+		Direction string
+		Offsets   []float64
+	}
+	var testData []data = []data{data{"", []float64{0, 100, 200}},
+		data{LeftToRight, []float64{0, 100, 200}},
+		data{RightToLeft, []float64{200, 100, 0}},
+	}
+	for _, d := range testData {
+		props.Direction = d.Direction
+		for column, offset := range d.Offsets {
+			if x := props.columnOffset(column); x != offset {
+				t.Errorf("%q: column %d is at %g, want %g", d.Direction, column, x, offset)
+			}
+		}
+	}
+}
+
+func TestMixedDirectionParagraph(t *testing.T) {
+	// A Hebrew paragraph with an English word in it runs right to left, so its lines
+	// are indented from the right, and its last line is set at the right.
+	text := "שלום Hello עולם, זה משפט ארוך בעברית עם מילה אחת באנגלית בתוכו."
+	rtl := rightToLeft(text, AutoDirection)
+	if !rtl {
+		t.Fatalf("the paragraph does not run right to left")
+	}
+	props := TypesettingProps{Alignment: AlignJustify}
+	type data struct {
+		Last      bool
+		LineWidth float64
+		Indent    float64
+		Offset    float64
+	}
+	var testData []data = []data{data{false, 280, 20, 0},
+		data{false, 300, 0, 0},
+		data{true, 100, 0, 200},
+		data{true, 100, 20, 180},
+	}
+	for _, d := range testData {
+		align := props.lineAlignment(d.Last, rtl)
+		if offset := lineOffset(align, rtl, 300, d.LineWidth, d.Indent); offset != d.Offset {
+			t.Errorf("line %v is set at %g", d, offset)
+		}
+	}
+}
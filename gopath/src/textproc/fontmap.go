@@ -0,0 +1,193 @@
+package textproc
+
+/*
+#cgo pkg-config: fontconfig
+#cgo pkg-config: pango pangocairo
+#include <stdlib.h>
+#include <fontconfig/fontconfig.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// fontMap is the font map that text is laid out with. It is made again when fonts
+// are added, as a font map does not see fonts added after it was made.
+var (
+	fontMapMutex sync.Mutex
+	fontMap      *C.PangoFontMap
+)
+
+// renderMutex serializes laying out and drawing text, as a font map is not safe to use
+// from several threads, and handlers run on several. A text object holds it from when
+// it is made until it is closed, and fonts are added while holding it.
+var renderMutex sync.Mutex
+
+// currentFontMap returns a reference to the font map that text is laid out with.
+// The caller must release it with g_object_unref.
+func currentFontMap() *C.PangoFontMap {
+	fontMapMutex.Lock()
+	defer fontMapMutex.Unlock()
+	if fontMap == nil {
+		fontMap = C.pango_cairo_font_map_new()
+	}
+	return (*C.PangoFontMap)(C.g_object_ref(C.gpointer(fontMap)))
+}
+
+// fontsChanged makes a new font map and font catalog, with the fonts added to fontconfig.
+func fontsChanged() {
+	fontMapMutex.Lock()
+	if fontMap != nil {
+		C.g_object_unref(C.gpointer(fontMap))
+		fontMap = nil
+	}
+	fontMapMutex.Unlock()
+	Fonts.Refresh()
+}
+
+// appFontDirs and appFontFiles are the fonts added to those installed, which are
+// added again when fontconfig is reinitialized. renderMutex guards them.
+var (
+	appFontDirs  []string
+	appFontFiles []string
+)
+
+// addFontDir adds the fonts in a directory to fontconfig's.
+func addFontDir(dir string) error {
+	cdir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cdir))
+	if C.FcConfigAppFontAddDir(nil, (*C.FcChar8)(unsafe.Pointer(cdir))) == C.FcFalse {
+		return errors.New("Could not add font directory " + dir)
+	}
+	return nil
+}
+
+// addFontFile adds the fonts in a file to fontconfig's.
+func addFontFile(file string) error {
+	cfile := C.CString(file)
+	defer C.free(unsafe.Pointer(cfile))
+	if C.FcConfigAppFontAddFile(nil, (*C.FcChar8)(unsafe.Pointer(cfile))) == C.FcFalse {
+		return errors.New("Could not add font file " + file)
+	}
+	return nil
+}
+
+// AddFontDir makes the fonts in a directory available as well as those installed.
+func AddFontDir(dir string) error {
+	renderMutex.Lock()
+	defer renderMutex.Unlock()
+	if err := addFontDir(dir); err != nil {
+		return err
+	}
+	appFontDirs = append(appFontDirs, dir)
+	fontsChanged()
+	return nil
+}
+
+// AddFontFile makes the fonts in a file available as well as those installed.
+func AddFontFile(file string) error {
+	renderMutex.Lock()
+	defer renderMutex.Unlock()
+	if err := addFontFile(file); err != nil {
+		return err
+	}
+	appFontFiles = append(appFontFiles, file)
+	fontsChanged()
+	return nil
+}
+
+// RefreshFonts has fontconfig look for fonts installed or removed since it last did,
+// and makes a new font map and font catalog with the fonts it finds. Fonts added
+// with AddFontDir and AddFontFile are kept.
+func RefreshFonts() error {
+	renderMutex.Lock()
+	defer renderMutex.Unlock()
+	if C.FcConfigUptoDate(nil) == C.FcFalse {
+		// Reinitializing drops the fonts that were added, so they are added again.
+		if C.FcInitReinitialize() == C.FcFalse {
+			return errors.New("Could not reload the font configuration")
+		}
+		for _, dir := range appFontDirs {
+			if err := addFontDir(dir); err != nil {
+				return err
+			}
+		}
+		for _, file := range appFontFiles {
+			if err := addFontFile(file); err != nil {
+				return err
+			}
+		}
+	}
+	fontsChanged()
+	return nil
+}
+
+// FontFileFace is a face in a font file.
+type FontFileFace struct {
+	Family string
+	Style  string
+}
+
+// patternString returns the first string value of an object of a fontconfig pattern.
+func patternString(pattern *C.FcPattern, object string) string {
+	cobject := C.CString(object)
+	defer C.free(unsafe.Pointer(cobject))
+	var s *C.FcChar8
+	if C.FcPatternGetString(pattern, cobject, 0, &s) != C.FcResultMatch {
+		return ""
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(s)))
+}
+
+// FontFileFaces returns the faces in a font file, as fontconfig reads it.
+// It fails if fontconfig cannot read any.
+func FontFileFaces(file string) ([]FontFileFace, error) {
+	cfile := C.CString(file)
+	defer C.free(unsafe.Pointer(cfile))
+	set := C.FcFontSetCreate()
+	defer C.FcFontSetDestroy(set)
+	C.FcFileScan(set, nil, nil, nil, (*C.FcChar8)(unsafe.Pointer(cfile)), C.FcTrue)
+	var faces []FontFileFace
+	n := int(set.nfont)
+	if n > 0 {
+		patterns := (*[1 << 20]*C.FcPattern)(unsafe.Pointer(set.fonts))[:n:n]
+		for _, pattern := range patterns {
+			faces = append(faces, FontFileFace{patternString(pattern, C.FC_FAMILY), patternString(pattern, C.FC_STYLE)})
+		}
+	}
+	if len(faces) == 0 {
+		return nil, errors.New("Not a font file that can be read")
+	}
+	return faces, nil
+}
+
+// fontSignatures are the first bytes of font files, and their extensions.
+var fontSignatures = []struct {
+	signature []byte
+	ext       string
+}{
+	{[]byte("\x00\x01\x00\x00"), ".ttf"},
+	{[]byte("true"), ".ttf"},
+	{[]byte("OTTO"), ".otf"},
+	{[]byte("ttcf"), ".ttc"},
+}
+
+// FontFileExtension returns the extension of a TrueType or OpenType font file
+// or collection, from its data. Other files, including WOFF files, are refused.
+func FontFileExtension(data []byte) (string, error) {
+	for _, s := range fontSignatures {
+		if bytes.HasPrefix(data, s.signature) {
+			return s.ext, nil
+		}
+	}
+	if bytes.HasPrefix(data, []byte("wOFF")) || bytes.HasPrefix(data, []byte("wOF2")) {
+		return "", errors.New("WOFF fonts are not supported; upload a TrueType or OpenType font")
+	}
+	return "", errors.New("Not a TrueType or OpenType font")
+}
@@ -0,0 +1,28 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestFontFileExtension(t *testing.T) {
+	type data struct {
+		Data string
+		Ext  string
+		Ok   bool
+	}
+	var testData []data = []data{data{"\x00\x01\x00\x00\x00\x10", ".ttf", true},
+		data{"OTTO\x00\x10", ".otf", true},
+		data{"ttcf\x00\x02", ".ttc", true},
+		data{"true\x00\x10", ".ttf", true},
+		data{"wOFF\x00\x01", "", false},
+		data{"wOF2\x00\x01", "", false},
+		data{"\x89PNG\r\n", "", false},
+		data{"", "", false},
+	}
+	for _, d := range testData {
+		ext, err := FontFileExtension([]byte(d.Data))
+		if (err == nil) != d.Ok || ext != d.Ext {
+			t.Errorf("FontFileExtension(%q) returned %q, %v", d.Data, ext, err)
+		}
+	}
+}
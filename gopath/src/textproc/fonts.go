@@ -0,0 +1,196 @@
+package textproc
+
+/*
+#cgo pkg-config: pango
+#include <stdlib.h>
+#include <pango/pango.h>
+
+PangoFontFace *indexFace(PangoFontFace **array, int i);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// The Pango font styles, stretches and variants, by name.
+var (
+	fontStyles = map[string]C.PangoStyle{
+		"normal":  C.PANGO_STYLE_NORMAL,
+		"italic":  C.PANGO_STYLE_ITALIC,
+		"oblique": C.PANGO_STYLE_OBLIQUE,
+	}
+	fontStretches = map[string]C.PangoStretch{
+		"ultra-condensed": C.PANGO_STRETCH_ULTRA_CONDENSED,
+		"extra-condensed": C.PANGO_STRETCH_EXTRA_CONDENSED,
+		"condensed":       C.PANGO_STRETCH_CONDENSED,
+		"semi-condensed":  C.PANGO_STRETCH_SEMI_CONDENSED,
+		"normal":          C.PANGO_STRETCH_NORMAL,
+		"semi-expanded":   C.PANGO_STRETCH_SEMI_EXPANDED,
+		"expanded":        C.PANGO_STRETCH_EXPANDED,
+		"extra-expanded":  C.PANGO_STRETCH_EXTRA_EXPANDED,
+		"ultra-expanded":  C.PANGO_STRETCH_ULTRA_EXPANDED,
+	}
+	fontVariants = map[string]C.PangoVariant{
+		"normal":     C.PANGO_VARIANT_NORMAL,
+		"small-caps": C.PANGO_VARIANT_SMALL_CAPS,
+	}
+)
+
+// normalWeight is the weight of a font that is neither light nor bold.
+const normalWeight = 400
+
+// FontFace is a face of a font family, as installed.
+type FontFace struct {
+	Name    string
+	Weight  int
+	Style   string
+	Stretch string
+}
+
+// String returns the name of the face, with its weight, style and stretch.
+func (f FontFace) String() string {
+	return fmt.Sprintf("%s (%d %s %s)", f.Name, f.Weight, f.Style, f.Stretch)
+}
+
+// styleName returns the name of a Pango style.
+func styleName(style C.PangoStyle) string {
+	for name, s := range fontStyles {
+		if s == style {
+			return name
+		}
+	}
+	return "normal"
+}
+
+// stretchName returns the name of a Pango stretch.
+func stretchName(stretch C.PangoStretch) string {
+	for name, s := range fontStretches {
+		if s == stretch {
+			return name
+		}
+	}
+	return "normal"
+}
+
+// familyFaces returns the faces of a font family, leaving out those that Pango
+// would synthesize.
+func familyFaces(family *C.PangoFontFamily) []FontFace {
+	var faces []FontFace
+	var cfaces **C.PangoFontFace
+	var nfaces C.int
+	C.pango_font_family_list_faces(family, &cfaces, &nfaces)
+	defer C.g_free(C.gpointer(cfaces))
+	for i := 0; i < int(nfaces); i++ {
+		face := C.indexFace(cfaces, C.int(i))
+		if C.pango_font_face_is_synthesized(face) != C.FALSE {
+			continue
+		}
+		desc := C.pango_font_face_describe(face)
+		faces = append(faces, FontFace{
+			Name:    C.GoString(C.pango_font_face_get_face_name(face)),
+			Weight:  int(C.pango_font_description_get_weight(desc)),
+			Style:   styleName(C.pango_font_description_get_style(desc)),
+			Stretch: stretchName(C.pango_font_description_get_stretch(desc)),
+		})
+		C.pango_font_description_free(desc)
+	}
+	return faces
+}
+
+// fontWeight returns the weight of props, with no weight being normal.
+func (props TypesettingProps) fontWeight() int {
+	if props.FontWeight == 0 {
+		return normalWeight
+	}
+	return props.FontWeight
+}
+
+// normalName returns name, or "normal" if it is empty.
+func normalName(name string) string {
+	if name == "" {
+		return "normal"
+	}
+	return name
+}
+
+// matchFace returns true if one of faces has the weight, style and stretch asked for.
+func matchFace(faces []FontFace, weight int, style, stretch string) bool {
+	for _, face := range faces {
+		if face.Weight == weight && face.Style == style && face.Stretch == stretch {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFont checks that the font family of props is installed with a face of the
+// weight, style and stretch of props, if they are not all normal. A small caps
+// variant need not have a face of its own, as Pango can make it from the others.
+func CheckFont(props TypesettingProps) error {
+	style, stretch := normalName(props.FontStyle), normalName(props.FontStretch)
+	if _, ok := fontStyles[style]; !ok {
+		return errors.New("Unknown font style " + style)
+	}
+	if _, ok := fontStretches[stretch]; !ok {
+		return errors.New("Unknown font stretch " + stretch)
+	}
+	if _, ok := fontVariants[normalName(props.FontVariant)]; !ok {
+		return errors.New("Unknown font variant " + props.FontVariant)
+	}
+	weight := props.fontWeight()
+	if weight == normalWeight && style == "normal" && stretch == "normal" {
+		return nil
+	}
+	family, ok := Fonts.Family(props.Fontname)
+	if !ok {
+		return errors.New("Font family " + props.Fontname + " is not installed")
+	}
+	if !matchFace(family.Faces, weight, style, stretch) {
+		var names []string
+		for _, face := range family.Faces {
+			names = append(names, face.String())
+		}
+		return fmt.Errorf("Font family %s has no face of weight %d, %s style and %s stretch; it has %s",
+			props.Fontname, weight, style, stretch, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// fontDescription returns the Pango font description of the font of props.
+// The caller must release it with pango_font_description_free.
+func (props TypesettingProps) fontDescription() *C.PangoFontDescription {
+	desc := C.pango_font_description_new()
+	cfontname := C.CString(props.Fontname)
+	defer C.free(unsafe.Pointer(cfontname))
+	C.pango_font_description_set_family(desc, cfontname)
+	C.pango_font_description_set_weight(desc, C.PangoWeight(props.fontWeight()))
+	C.pango_font_description_set_style(desc, fontStyles[normalName(props.FontStyle)])
+	C.pango_font_description_set_stretch(desc, fontStretches[normalName(props.FontStretch)])
+	C.pango_font_description_set_variant(desc, fontVariants[normalName(props.FontVariant)])
+	C.pango_font_description_set_absolute_size(desc, C.double(props.Fontsize)*C.PANGO_SCALE)
+	return desc
+}
+
+// setAttributes sets the attributes of a layout to attrs, which may be nil, with the
+// font features and letter spacing of props under them, so that markup overrides them.
+func (props TypesettingProps) setAttributes(layout *C.PangoLayout, attrs *C.PangoAttrList) {
+	if attrs == nil {
+		attrs = C.pango_attr_list_new()
+	} else {
+		attrs = C.pango_attr_list_copy(attrs)
+	}
+	defer C.pango_attr_list_unref(attrs)
+	if props.LetterSpacing != 0 {
+		C.pango_attr_list_insert_before(attrs, C.pango_attr_letter_spacing_new(C.int(props.LetterSpacing*C.PANGO_SCALE)))
+	}
+	if len(props.FontFeatures) > 0 {
+		cfeatures := C.CString(strings.Join(props.FontFeatures, ","))
+		defer C.free(unsafe.Pointer(cfeatures))
+		C.pango_attr_list_insert_before(attrs, C.pango_attr_font_features_new(cfeatures))
+	}
+	C.pango_layout_set_attributes(layout, attrs)
+}
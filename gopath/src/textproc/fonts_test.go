@@ -0,0 +1,30 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestMatchFace(t *testing.T) {
+	faces := []FontFace{FontFace{"Regular", 400, "normal", "normal"},
+		FontFace{"Italic", 400, "italic", "normal"},
+		FontFace{"Semibold", 600, "normal", "normal"},
+		FontFace{"Condensed Bold", 700, "normal", "condensed"}}
+	type data struct {
+		Weight  int
+		Style   string
+		Stretch string
+		Match   bool
+	}
+	var testData []data = []data{data{400, "normal", "normal", true},
+		data{400, "italic", "normal", true},
+		data{600, "normal", "normal", true},
+		data{600, "italic", "normal", false},
+		data{700, "normal", "normal", false},
+		data{700, "normal", "condensed", true},
+	}
+	for _, d := range testData {
+		if match := matchFace(faces, d.Weight, d.Style, d.Stretch); match != d.Match {
+			t.Errorf("matchFace(%d, %q, %q) was %v", d.Weight, d.Style, d.Stretch, match)
+		}
+	}
+}
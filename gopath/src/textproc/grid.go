@@ -0,0 +1,77 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#include <cairo.h>
+*/
+import "C"
+
+import (
+	"math"
+)
+
+// onGrid returns y, or with BaselineGrid the first baseline of the grid at or below y.
+// The baselines of the grid are Baselineskip apart, from the first baseline of the page.
+func (props TypesettingProps) onGrid(y float64) float64 {
+	if !props.BaselineGrid || props.Baselineskip <= 0 {
+		return y
+	}
+	first := props.FirstBaseline()
+	// Allow for rounding, so that a baseline on the grid stays where it is.
+	k := math.Ceil((y-first)/props.Baselineskip - 1e-6)
+	if k < 0 {
+		k = 0
+	}
+	return first + k*props.Baselineskip
+}
+
+// gridFloor returns a distance down a column, or with BaselineGrid the largest multiple
+// of Baselineskip not more than it, so that moving lines up by it keeps them on the grid.
+func (props TypesettingProps) gridFloor(offset float64) float64 {
+	if !props.BaselineGrid || props.Baselineskip <= 0 {
+		return offset
+	}
+	return math.Floor(offset/props.Baselineskip+1e-6) * props.Baselineskip
+}
+
+// gridBaselines returns the baselines of the grid of props on a page.
+func (props TypesettingProps) gridBaselines() []float64 {
+	var lines []float64
+	if props.Baselineskip <= 0 {
+		return lines
+	}
+	for y := props.FirstBaseline(); y <= props.LastBaseline()+1e-6; y += props.Baselineskip {
+		lines = append(lines, y)
+	}
+	return lines
+}
+
+// ShowBaselineGrid draws the baseline grid of props across the text block of every page,
+// under the text, for checking that lines are on it.
+func (t *StreamTextObject) ShowBaselineGrid(props TypesettingProps) {
+	t.gridProps = &props
+}
+
+// pageGridProps returns the properties of the baseline grid on the current page,
+// which has its own size.
+func (t *StreamTextObject) pageGridProps() TypesettingProps {
+	props := *t.gridProps
+	props.PageWidth = t.width
+	props.PageHeight = t.height
+	return props
+}
+
+// writeGrid draws the baseline grid on the current page, if it is shown.
+func (t *StreamTextObject) writeGrid() {
+	if t.gridProps == nil {
+		return
+	}
+	props := t.pageGridProps()
+	C.cairo_set_source_rgb(t.canvas, 0.5, 0.8, 1)
+	C.cairo_set_line_width(t.canvas, 0.25)
+	for _, y := range props.gridBaselines() {
+		C.cairo_move_to(t.canvas, C.double(props.LeftMargin), C.double(y))
+		C.cairo_line_to(t.canvas, C.double(props.PageWidth-props.RightMargin), C.double(y))
+	}
+	C.cairo_stroke(t.canvas)
+}
@@ -0,0 +1,78 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestOnGrid(t *testing.T) {
+	type data struct {
+		Grid bool
+		Y    float64
+		OnY  float64
+	}
+	// The first baseline is at 82, and the baselines are 15 apart.
+	var testData []data = []data{data{false, 90, 90},
+		data{true, 82, 82},
+		data{true, 83, 97},
+		data{true, 97, 97},
+		data{true, 96.9999999, 97},
+		data{true, 120, 127},
+		data{true, 50, 82},
+	}
+	for _, d := range testData {
+		props := TypesettingProps{TopMargin: 72, Fontsize: 10, Baselineskip: 15, BaselineGrid: d.Grid}
+		if y := props.onGrid(d.Y); y != d.OnY {
+			t.Errorf("onGrid(%v) with grid %v was %v, not %v", d.Y, d.Grid, y, d.OnY)
+		}
+	}
+}
+
+func TestGridFloor(t *testing.T) {
+	type data struct {
+		Grid   bool
+		Offset float64
+		Floor  float64
+	}
+	var testData []data = []data{data{false, 40, 40},
+		data{true, 0, 0},
+		data{true, 40, 30},
+		data{true, 45, 45},
+		data{true, 44.9999999, 45},
+		data{true, 14, 0},
+	}
+	for _, d := range testData {
+		props := TypesettingProps{Baselineskip: 15, BaselineGrid: d.Grid}
+		if floor := props.gridFloor(d.Offset); floor != d.Floor {
+			t.Errorf("gridFloor(%v) with grid %v was %v, not %v", d.Offset, d.Grid, floor, d.Floor)
+		}
+	}
+}
+
+func TestGridBaselines(t *testing.T) {
+	props := TypesettingProps{TopMargin: 72, BottomMargin: 72, PageHeight: 202, Fontsize: 10, Baselineskip: 16}
+	lines := props.gridBaselines()
+	expected := []float64{82, 98, 114, 130}
+	if len(lines) != len(expected) {
+		t.Fatalf("gridBaselines was %v, not %v", lines, expected)
+	}
+	for i, y := range lines {
+		if y != expected[i] {
+			t.Errorf("gridBaselines was %v, not %v", lines, expected)
+		}
+	}
+}
+
+func TestPageGrid(t *testing.T) {
+	// The grid of a page resized with \pagesize follows its size, not the document's.
+	props := TypesettingProps{LeftMargin: 72, RightMargin: 72, TopMargin: 72, BottomMargin: 72,
+		PageWidth: 612, PageHeight: 792, Fontsize: 10, Baselineskip: 16}
+	obj := &StreamTextObject{width: 400, height: 202}
+	obj.ShowBaselineGrid(props)
+	grid := obj.pageGridProps()
+	if width := grid.PageWidth - grid.RightMargin - grid.LeftMargin; width != 256 {
+		t.Errorf("grid is %g wide", width)
+	}
+	if lines := grid.gridBaselines(); len(lines) != 4 {
+		t.Errorf("grid has baselines %v", lines)
+	}
+}
@@ -0,0 +1,124 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#cgo pkg-config: pango pangocairo
+#include <cairo.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+*/
+import "C"
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RunningHead is the text of a header or footer. Left is set at the left margin,
+// Center in the middle of the text block and Right at the right margin.
+type RunningHead struct {
+	Left   string
+	Center string
+	Right  string
+}
+
+// empty returns true if a running head has no text.
+func (h RunningHead) empty() bool {
+	return h.Left == "" && h.Center == "" && h.Right == ""
+}
+
+// PageHeads are the header and footer set on each page.
+// Their text is plain text, with the placeholders {page}, {pages}, {title} and {date}.
+type PageHeads struct {
+	Header RunningHead
+	Footer RunningHead
+	// HeaderSkip is the distance from the baseline of the header up from the top margin.
+	// FooterSkip is the distance from the lowest baseline down to that of the footer.
+	HeaderSkip float64
+	FooterSkip float64
+	// SuppressFirstPage leaves the first page without a header or footer.
+	SuppressFirstPage bool
+	// Title and Date replace {title} and {date}.
+	Title string
+	Date  string
+	// Pages replaces {pages}. It is the page count of an earlier run of the document.
+	Pages int
+}
+
+// NeedsPageCount returns true if the heads use the {pages} placeholder, so that
+// the document must be typeset once to count its pages before it is typeset with them.
+func (heads PageHeads) NeedsPageCount() bool {
+	for _, h := range []RunningHead{heads.Header, heads.Footer} {
+		for _, s := range []string{h.Left, h.Center, h.Right} {
+			if strings.Contains(s, "{pages}") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expand replaces the placeholders in the text of a head on the given page.
+func (heads PageHeads) expand(text string, page int) string {
+	r := strings.NewReplacer("{page}", strconv.Itoa(page), "{pages}", strconv.Itoa(heads.Pages),
+		"{title}", heads.Title, "{date}", heads.Date)
+	return r.Replace(text)
+}
+
+// SetPageHeads sets the header and footer of every page, in the font and margins of props.
+// They are set on each page as it is finished.
+func (t *StreamTextObject) SetPageHeads(heads PageHeads, props TypesettingProps) {
+	props.Markup = false
+	props.LeftIndent = 0
+	props.Label = ""
+	t.heads = &heads
+	t.headProps = props
+}
+
+// PageCount returns the number of pages that have been started.
+func (t *StreamTextObject) PageCount() int {
+	return t.pages
+}
+
+// writeHeads sets the header and footer of the current page.
+func (t *StreamTextObject) writeHeads() {
+	if t.heads == nil || (t.pages == 1 && t.heads.SuppressFirstPage) {
+		return
+	}
+	props := t.headProps
+	props.PageWidth = t.width
+	props.PageHeight = t.height
+	t.setColor(props.TextColor)
+	if !t.heads.Header.empty() {
+		t.writeHead(t.heads.Header, props, props.TopMargin-t.heads.HeaderSkip)
+	}
+	if !t.heads.Footer.empty() {
+		t.writeHead(t.heads.Footer, props, props.LastBaseline()+t.heads.FooterSkip)
+	}
+}
+
+// writeHead sets the three parts of a running head on the baseline y.
+func (t *StreamTextObject) writeHead(head RunningHead, props TypesettingProps, y float64) {
+	left := props.LeftMargin
+	right := props.PageWidth - props.RightMargin
+	parts := []struct {
+		text string
+		// align is the fraction of the text's width to the left of its position.
+		align float64
+		x     float64
+	}{
+		{head.Left, 0, left},
+		{head.Center, 0.5, (left + right) / 2},
+		{head.Right, 1, right},
+	}
+	for _, part := range parts {
+		if part.text == "" {
+			continue
+		}
+		layout := t.makeLayout(t.heads.expand(part.text, t.pages), props, -1)
+		x := part.x - part.align*layoutWidth(layout)
+		C.cairo_move_to(t.canvas, C.double(x), C.double(y))
+		C.pango_cairo_show_layout_line(t.canvas, C.pango_layout_get_line(layout, 0))
+		C.g_object_unref(C.gpointer(layout))
+	}
+}
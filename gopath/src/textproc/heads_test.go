@@ -0,0 +1,36 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestExpandHeads(t *testing.T) {
+	heads := PageHeads{Title: "Report", Date: "May 1, 2013", Pages: 12}
+	type data struct {
+		Text     string
+		Page     int
+		Expanded string
+	}
+	var testData []data = []data{data{"Page {page} of {pages}", 3, "Page 3 of 12"},
+		data{"{title}", 1, "Report"},
+		data{"{title}, {date}", 2, "Report, May 1, 2013"},
+		data{"{page}{page}", 7, "77"},
+		data{"{chapter}", 1, "{chapter}"},
+	}
+	for _, d := range testData {
+		if s := heads.expand(d.Text, d.Page); s != d.Expanded {
+			t.Errorf("expand(%q, %d) = %q, want %q", d.Text, d.Page, s, d.Expanded)
+		}
+	}
+}
+
+func TestNeedsPageCount(t *testing.T) {
+	heads := PageHeads{Footer: RunningHead{Center: "{page}"}}
+	if heads.NeedsPageCount() {
+		t.Errorf("NeedsPageCount() is true without {pages}")
+	}
+	heads.Header.Right = "{page}/{pages}"
+	if !heads.NeedsPageCount() {
+		t.Errorf("NeedsPageCount() is false with {pages}")
+	}
+}
@@ -0,0 +1,129 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#include <cairo.h>
+
+cairo_surface_t *gocairo_image_surface_create_from_png_data(const unsigned char *data, unsigned int length);
+cairo_status_t gocairo_surface_set_jpeg_data(cairo_surface_t *surface, const unsigned char *data, unsigned long length);
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"image/jpeg"
+	"unsafe"
+)
+
+// imageSurface returns a cairo image surface for PNG or JPEG image data.
+// A JPEG image is decoded for drawing, and its data is attached to the surface,
+// so that backends that can embed JPEG images (like PDF) use it unchanged.
+// The caller must release the surface with cairo_surface_destroy.
+func imageSurface(data []byte, contentType string) (*C.cairo_surface_t, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Empty image")
+	}
+	cdata := (*C.uchar)(unsafe.Pointer(&data[0]))
+	switch contentType {
+	case "image/png":
+		surface := C.gocairo_image_surface_create_from_png_data(cdata, C.uint(len(data)))
+		if C.cairo_surface_status(surface) != C.CAIRO_STATUS_SUCCESS {
+			C.cairo_surface_destroy(surface)
+			return nil, errors.New("Could not read PNG image")
+		}
+		return surface, nil
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("Could not read JPEG image: " + err.Error())
+		}
+		bounds := img.Bounds()
+		width, height := bounds.Dx(), bounds.Dy()
+		surface := C.cairo_image_surface_create(C.CAIRO_FORMAT_RGB24, C.int(width), C.int(height))
+		if C.cairo_surface_status(surface) != C.CAIRO_STATUS_SUCCESS {
+			C.cairo_surface_destroy(surface)
+			return nil, errors.New("Could not create image surface")
+		}
+		C.cairo_surface_flush(surface)
+		stride := int(C.cairo_image_surface_get_stride(surface))
+		size := stride * height
+		pixels := (*[1 << 30]byte)(unsafe.Pointer(C.cairo_image_surface_get_data(surface)))[:size:size]
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				// Pixels are native-endian 32-bit values.
+				pixel := (*uint32)(unsafe.Pointer(&pixels[y*stride+x*4]))
+				*pixel = (r>>8)<<16 | (g>>8)<<8 | b>>8
+			}
+		}
+		C.cairo_surface_mark_dirty(surface)
+		if C.gocairo_surface_set_jpeg_data(surface, cdata, C.ulong(len(data))) != C.CAIRO_STATUS_SUCCESS {
+			C.cairo_surface_destroy(surface)
+			return nil, errors.New("Could not attach JPEG data")
+		}
+		return surface, nil
+	}
+	return nil, errors.New("Unsupported image type " + contentType)
+}
+
+// imageSize returns the size of an image of pixels, set at width by height.
+// If either is zero it follows from the other, and if both are, each pixel is a point.
+// The image is then scaled down if need be to fit in maxWidth by maxHeight.
+func imageSize(pixelWidth, pixelHeight int, width, height, maxWidth, maxHeight float64) (float64, float64) {
+	pw, ph := float64(pixelWidth), float64(pixelHeight)
+	switch {
+	case width == 0 && height == 0:
+		width, height = pw, ph
+	case width == 0:
+		width = height * pw / ph
+	case height == 0:
+		height = width * ph / pw
+	}
+	if width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	return width, height
+}
+
+// WriteImage sets an image below the last thing written, centered in the column,
+// at the size given by imageSize. Its contentType is "image/png" or "image/jpeg".
+func (t *StreamTextObject) WriteImage(data []byte, contentType string, width, height float64, props TypesettingProps) error {
+	surface, err := imageSurface(data, contentType)
+	if err != nil {
+		return err
+	}
+	t.surfaces = append(t.surfaces, surface)
+	pw := int(C.cairo_image_surface_get_width(surface))
+	ph := int(C.cairo_image_surface_get_height(surface))
+	if pw == 0 || ph == 0 {
+		return errors.New("Empty image")
+	}
+	width, height = imageSize(pw, ph, width, height, props.TextWidth(), props.LastBaseline()-props.TopMargin)
+
+	if t.blank {
+		t.y = props.FirstBaseline()
+	} else {
+		t.y = props.onGrid(t.y + props.ParSkip)
+	}
+	// The top of the image is at the top of the line it takes the place of.
+	top := t.y - props.Fontsize
+	if top+height > t.textProps(props).LastBaseline() && !t.columnEmpty() {
+		t.nextColumn(props)
+		top = t.y - props.Fontsize
+	}
+	x := props.LeftMargin + props.LeftIndent + (props.TextWidth()-width)/2
+	t.place(top+height, props, func(dx float64, y float64) {
+		C.cairo_save(t.canvas)
+		C.cairo_translate(t.canvas, C.double(x+dx), C.double(y-height))
+		C.cairo_scale(t.canvas, C.double(width/float64(pw)), C.double(height/float64(ph)))
+		C.cairo_set_source_surface(t.canvas, surface, 0, 0)
+		C.cairo_paint(t.canvas)
+		C.cairo_restore(t.canvas)
+	})
+	t.y = top + height + props.Baselineskip
+	return nil
+}
@@ -0,0 +1,29 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestImageSize(t *testing.T) {
+	type data struct {
+		PixelWidth, PixelHeight int
+		Width, Height           float64
+		ResultWidth             float64
+		ResultHeight            float64
+	}
+	var testData []data = []data{data{200, 100, 0, 0, 200, 100},
+		data{200, 100, 100, 0, 100, 50},
+		data{200, 100, 0, 100, 200, 100},
+		data{200, 100, 300, 300, 300, 300},
+		// Too wide for the 400pt text width.
+		data{200, 100, 800, 0, 400, 200},
+		// Too tall for the 500pt text height.
+		data{100, 200, 0, 1000, 250, 500},
+	}
+	for _, d := range testData {
+		w, h := imageSize(d.PixelWidth, d.PixelHeight, d.Width, d.Height, 400, 500)
+		if w != d.ResultWidth || h != d.ResultHeight {
+			t.Errorf("imageSize(%d, %d, %g, %g) = %g, %g", d.PixelWidth, d.PixelHeight, d.Width, d.Height, w, h)
+		}
+	}
+}
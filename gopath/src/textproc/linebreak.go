@@ -0,0 +1,261 @@
+package textproc
+
+// This file implements the Knuth-Plass total-fit line breaking algorithm,
+// from "Breaking Paragraphs into Lines", Software—Practice and Experience 11 (1981).
+// A paragraph is a list of boxes, glue and penalties, and the algorithm finds
+// the breaks that minimize the total demerits of all its lines.
+
+import (
+	"math"
+)
+
+// itemKind is the "enum" type for kinds of line breaking items.
+type itemKind int
+
+// an enumeration for item kinds.
+const (
+	boxItem itemKind = iota
+	glueItem
+	penaltyItem
+)
+
+// infinitePenalty is the penalty that prohibits a break. Its negative forces one.
+const infinitePenalty = 10000
+
+// fillStretch is the stretchability of the glue that fills the last line of a paragraph.
+const fillStretch = 1e10
+
+// breakItem is a box, glue or penalty. Widths are in points.
+type breakItem struct {
+	kind    itemKind
+	width   float64
+	stretch float64
+	shrink  float64
+	penalty float64
+	// flagged is true for a penalty at a hyphen. Breaks at consecutive flagged
+	// penalties are discouraged.
+	flagged bool
+	// start and end are the byte range of the text of a box or penalty.
+	// They are both -1 for a box that is only space, like a paragraph indent.
+	start, end int
+}
+
+// breakParams are the parameters of the line breaking algorithm.
+type breakParams struct {
+	// tolerance is the greatest badness allowed for a line, as with TeX's \tolerance.
+	tolerance float64
+	// looseness asks for a paragraph this many lines longer (or, if negative,
+	// shorter) than the optimum, if that is possible.
+	looseness int
+	// linePenalty is added to the badness of every line.
+	linePenalty float64
+	// flaggedDemerits are added for consecutive lines ending in hyphens.
+	flaggedDemerits float64
+	// fitnessDemerits are added for adjacent lines of very different tightness.
+	fitnessDemerits float64
+}
+
+// defaultBreakParams returns TeX's defaults, with the given tolerance and looseness.
+func defaultBreakParams(tolerance float64, looseness int) breakParams {
+	return breakParams{tolerance: tolerance, looseness: looseness,
+		linePenalty: 10, flaggedDemerits: 3000, fitnessDemerits: 10000}
+}
+
+// lineBreak is a chosen break, at items[position], ending a line set with the
+// given adjustment ratio.
+type lineBreak struct {
+	position int
+	ratio    float64
+}
+
+// breakNode is a feasible break, and the best way found of reaching it.
+type breakNode struct {
+	position int
+	line     int
+	fitness  int
+	// The sums of widths, stretch and shrink of the items up to the start of the next line.
+	totalWidth   float64
+	totalStretch float64
+	totalShrink  float64
+	demerits     float64
+	ratio        float64
+	prev         *breakNode
+}
+
+// badness returns TeX's badness for an adjustment ratio.
+func badness(ratio float64) float64 {
+	if ratio < -1 {
+		return math.Inf(1)
+	}
+	return 100 * math.Pow(math.Abs(ratio), 3)
+}
+
+// fitnessClass returns the fitness class of a line: 0 for tight lines, 1 for
+// decent ones, 2 for loose ones and 3 for very loose ones.
+func fitnessClass(ratio float64) int {
+	switch {
+	case ratio < -0.5:
+		return 0
+	case ratio <= 0.5:
+		return 1
+	case ratio <= 1:
+		return 2
+	}
+	return 3
+}
+
+// breakLines finds the best breaks for items, set in lines of the given width.
+// The last item must be a forced break. It returns nil if there is no way of
+// breaking the paragraph within the tolerance.
+func breakLines(items []breakItem, width float64, params breakParams) []lineBreak {
+	var sumWidth, sumStretch, sumShrink float64
+	active := []*breakNode{&breakNode{position: -1, fitness: 1}}
+
+	// ratio returns the adjustment ratio of a line from a to the break at b.
+	ratio := func(a *breakNode, b int) float64 {
+		length := sumWidth - a.totalWidth
+		if items[b].kind == penaltyItem {
+			length += items[b].width
+		}
+		switch {
+		case length < width:
+			if stretch := sumStretch - a.totalStretch; stretch > 0 {
+				return (width - length) / stretch
+			}
+			return math.Inf(1)
+		case length > width:
+			if shrink := sumShrink - a.totalShrink; shrink > 0 {
+				return (width - length) / shrink
+			}
+			return math.Inf(-1)
+		}
+		return 0
+	}
+
+	// totalsAfter returns the sums to the first box after a break at b.
+	totalsAfter := func(b int) (float64, float64, float64) {
+		w, y, z := sumWidth, sumStretch, sumShrink
+		for i := b; i < len(items); i++ {
+			item := items[i]
+			if item.kind == boxItem || (item.kind == penaltyItem && item.penalty <= -infinitePenalty && i > b) {
+				break
+			}
+			if item.kind == glueItem {
+				w += item.width
+				y += item.stretch
+				z += item.shrink
+			}
+		}
+		return w, y, z
+	}
+
+	tryBreak := func(b int) {
+		item := items[b]
+		forced := item.kind == penaltyItem && item.penalty <= -infinitePenalty
+		// The best new node for each fitness class and line number.
+		type key struct{ fitness, line int }
+		best := make(map[key]*breakNode)
+		var keys []key
+		var remaining []*breakNode
+		for _, a := range active {
+			r := ratio(a, b)
+			if r < -1 || forced {
+				// The line is too long, and so will be every later line starting at a.
+			} else {
+				remaining = append(remaining, a)
+			}
+			if r < -1 || badness(r) > params.tolerance {
+				continue
+			}
+			d := params.linePenalty + badness(r)
+			d *= d
+			switch {
+			case item.kind == penaltyItem && item.penalty >= 0:
+				d += item.penalty * item.penalty
+			case item.kind == penaltyItem && item.penalty > -infinitePenalty:
+				d -= item.penalty * item.penalty
+			}
+			if a.position >= 0 && items[a.position].flagged && item.flagged {
+				d += params.flaggedDemerits
+			}
+			fitness := fitnessClass(r)
+			if fitness-a.fitness > 1 || a.fitness-fitness > 1 {
+				d += params.fitnessDemerits
+			}
+			d += a.demerits
+			k := key{fitness, a.line + 1}
+			if node, ok := best[k]; !ok || d < node.demerits {
+				if !ok {
+					keys = append(keys, k)
+				}
+				best[k] = &breakNode{position: b, line: a.line + 1, fitness: fitness,
+					demerits: d, ratio: r, prev: a}
+			}
+		}
+		for _, k := range keys {
+			node := best[k]
+			node.totalWidth, node.totalStretch, node.totalShrink = totalsAfter(b)
+			remaining = append(remaining, node)
+		}
+		active = remaining
+	}
+
+	for i, item := range items {
+		switch item.kind {
+		case boxItem:
+			sumWidth += item.width
+		case glueItem:
+			if i > 0 && items[i-1].kind == boxItem {
+				tryBreak(i)
+			}
+			sumWidth += item.width
+			sumStretch += item.stretch
+			sumShrink += item.shrink
+		case penaltyItem:
+			if item.penalty < infinitePenalty {
+				tryBreak(i)
+			}
+		}
+		if len(active) == 0 {
+			return nil
+		}
+	}
+
+	// Choose the final node, taking looseness into account.
+	var chosen *breakNode
+	for _, node := range active {
+		if chosen == nil || node.demerits < chosen.demerits {
+			chosen = node
+		}
+	}
+	if params.looseness != 0 {
+		target := chosen.line + params.looseness
+		for _, node := range active {
+			d, dc := abs(node.line-target), abs(chosen.line-target)
+			if d < dc || (d == dc && node.demerits < chosen.demerits) {
+				chosen = node
+			}
+		}
+	}
+
+	var breaks []lineBreak
+	for node := chosen; node.prev != nil; node = node.prev {
+		breaks = append([]lineBreak{lineBreak{node.position, node.ratio}}, breaks...)
+	}
+	return breaks
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// adjustedGlue returns the width of glue on a line set with the given adjustment ratio.
+func adjustedGlue(item breakItem, ratio float64) float64 {
+	if ratio < 0 {
+		return item.width + ratio*item.shrink
+	}
+	return item.width + ratio*item.stretch
+}
@@ -0,0 +1,102 @@
+package textproc
+
+import (
+	"testing"
+)
+
+// makeItems returns items for words of the given widths, separated by glue,
+// and ended like a paragraph.
+func makeItems(widths []float64, glue breakItem) []breakItem {
+	var items []breakItem
+	for i, w := range widths {
+		if i > 0 {
+			items = append(items, glue)
+		}
+		items = append(items, breakItem{kind: boxItem, width: w})
+	}
+	items = append(items, breakItem{kind: glueItem, stretch: fillStretch},
+		breakItem{kind: penaltyItem, penalty: -infinitePenalty})
+	return items
+}
+
+// lineLengths returns the number of boxes on each line.
+func lineLengths(items []breakItem, breaks []lineBreak) []int {
+	var lengths []int
+	start := 0
+	for _, b := range breaks {
+		n := 0
+		for _, item := range items[start:b.position] {
+			if item.kind == boxItem {
+				n++
+			}
+		}
+		lengths = append(lengths, n)
+		start = b.position
+	}
+	return lengths
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBreakLines(t *testing.T) {
+	glue := breakItem{kind: glueItem, width: 10, stretch: 5, shrink: 3}
+	items := makeItems([]float64{30, 30, 30, 30, 30, 30}, glue)
+	breaks := breakLines(items, 75, defaultBreakParams(200, 0))
+	if lengths := lineLengths(items, breaks); !equalInts(lengths, []int{2, 2, 2}) {
+		t.Errorf("lines have %v words", lengths)
+	}
+	if len(breaks) > 0 && breaks[0].ratio != 1 {
+		t.Errorf("first line has ratio %g", breaks[0].ratio)
+	}
+	if breaks := breakLines(items, 50, defaultBreakParams(200, 0)); breaks != nil {
+		t.Errorf("found breaks for lines that are too narrow: %v", lineLengths(items, breaks))
+	}
+}
+
+func TestTotalFit(t *testing.T) {
+	// A greedy breaker fills the first line, leaving a very loose second one.
+	glue := breakItem{kind: glueItem, width: 10, stretch: 10, shrink: 3}
+	items := makeItems([]float64{30, 30, 30, 25, 25, 60}, glue)
+	breaks := breakLines(items, 110, defaultBreakParams(20000, 0))
+	if lengths := lineLengths(items, breaks); !equalInts(lengths, []int{2, 3, 1}) {
+		t.Errorf("lines have %v words", lengths)
+	}
+}
+
+func TestLooseness(t *testing.T) {
+	glue := breakItem{kind: glueItem, width: 10, stretch: 10, shrink: 3}
+	items := makeItems([]float64{20, 20, 20, 20, 20, 20}, glue)
+	breaks := breakLines(items, 100, defaultBreakParams(20000, 0))
+	if len(breaks) != 2 {
+		t.Errorf("paragraph has %d lines", len(breaks))
+	}
+	breaks = breakLines(items, 100, defaultBreakParams(20000, 1))
+	if len(breaks) != 3 {
+		t.Errorf("looser paragraph has %d lines", len(breaks))
+	}
+}
+
+func TestFlaggedBreaks(t *testing.T) {
+	// A hyphen penalty inside the third word is the only way of fitting the lines.
+	glue := breakItem{kind: glueItem, width: 10, stretch: 5, shrink: 3}
+	items := makeItems([]float64{30, 30, 40, 30}, glue)
+	third := 4
+	hyphenated := []breakItem{breakItem{kind: boxItem, width: 15},
+		breakItem{kind: penaltyItem, width: 5, penalty: 50, flagged: true},
+		breakItem{kind: boxItem, width: 25}}
+	items = append(items[:third], append(hyphenated, items[third+1:]...)...)
+	breaks := breakLines(items, 100, defaultBreakParams(200, 0))
+	if len(breaks) != 2 || !items[breaks[0].position].flagged {
+		t.Errorf("paragraph was not broken at the hyphen: %v", breaks)
+	}
+}
@@ -0,0 +1,180 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#cgo pkg-config: pango pangocairo
+#include <stdlib.h>
+#include <cairo.h>
+#include <cairo-pdf.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// LinkStart and LinkEnd mark the text of a link in the text of a paragraph.
+const (
+	LinkStart = "\ufff9"
+	LinkEnd   = "\ufffb"
+)
+
+// Link is the target of a link: a URI, or Dest, the Anchor of a paragraph.
+type Link struct {
+	URI  string
+	Dest string
+}
+
+// The names of cairo's link and destination tags, CAIRO_TAG_LINK and CAIRO_TAG_DEST.
+const (
+	tagLink = "Link"
+	tagDest = "cairo.dest"
+)
+
+// linkRange is a link, and the byte range of its text in the plain text of a paragraph.
+type linkRange struct {
+	link       Link
+	start, end int
+}
+
+// linkMarkRemover removes the marks of links.
+var linkMarkRemover = strings.NewReplacer(LinkStart, "", LinkEnd, "")
+
+// linkRanges returns the ranges of the links of a paragraph in its plain text,
+// once the marks of the links are removed. ok is false if the markup is invalid.
+func linkRanges(text string, markup bool, links []Link) (ranges []linkRange, ok bool) {
+	pt, ok := parseParagraphText(text, markup)
+	if !ok {
+		return nil, false
+	}
+	C.pango_attr_list_unref(pt.attrs)
+	return markedRanges(pt.text, links), true
+}
+
+// markedRanges returns the ranges of the links marked in plain text, once the marks are removed.
+func markedRanges(text string, links []Link) []linkRange {
+	var ranges []linkRange
+	removed := 0
+	start := -1
+	for i := 0; i < len(text) && len(ranges) < len(links); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], LinkStart):
+			start = i - removed
+			removed += len(LinkStart)
+		case strings.HasPrefix(text[i:], LinkEnd) && start >= 0:
+			ranges = append(ranges, linkRange{links[len(ranges)], start, i - removed})
+			removed += len(LinkEnd)
+			start = -1
+		}
+	}
+	return ranges
+}
+
+// tagString quotes a string as a value in the attributes of a cairo tag.
+func tagString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// linkAttributes returns the attributes of a cairo link tag to a link, over a rectangle.
+func linkAttributes(link Link, x, y, width, height float64) string {
+	attrs := fmt.Sprintf("rect=[%g %g %g %g] ", x, y, width, height)
+	if link.Dest != "" {
+		return attrs + "dest=" + tagString(link.Dest)
+	}
+	return attrs + "uri=" + tagString(link.URI)
+}
+
+// lineExtent returns the horizontal extent, from the start of line i of a paragraph,
+// of the part of the line in the byte range from start to end.
+func lineExtent(par paragraph, i int, start, end int) (x0, x1 float64) {
+	if par.lineOfIndex(start) == i {
+		x0 = par.indexX(i, start)
+	}
+	if par.lineOfIndex(end) == i {
+		x1 = par.indexX(i, end)
+	} else {
+		x1 = par.lineWidth(i)
+	}
+	return x0, x1
+}
+
+// writeLinks makes the links of line i of a paragraph, which is drawn at x on the baseline y.
+func (t *StreamTextObject) writeLinks(par paragraph, i int, links []linkRange, props TypesettingProps, x, y float64) {
+	for _, l := range links {
+		if par.lineOfIndex(l.start) > i || par.lineOfIndex(l.end) < i {
+			continue
+		}
+		x0, x1 := lineExtent(par, i, l.start, l.end)
+		if x1 < x0 {
+			// The text of the link runs right to left.
+			x0, x1 = x1, x0
+		}
+		if x1 == x0 {
+			continue
+		}
+		attrs := C.CString(linkAttributes(l.link, x+x0, y-props.Fontsize, x1-x0, props.Baselineskip))
+		tag := C.CString(tagLink)
+		C.cairo_tag_begin(t.canvas, tag, attrs)
+		C.cairo_tag_end(t.canvas, tag)
+		C.free(unsafe.Pointer(tag))
+		C.free(unsafe.Pointer(attrs))
+	}
+}
+
+// beginDest starts the destination of links named anchor. Drawing until endDest is its area.
+func (t *StreamTextObject) beginDest(anchor string) {
+	attrs := C.CString("name=" + tagString(anchor))
+	tag := C.CString(tagDest)
+	C.cairo_tag_begin(t.canvas, tag, attrs)
+	C.free(unsafe.Pointer(tag))
+	C.free(unsafe.Pointer(attrs))
+}
+
+// endDest ends the destination started by beginDest.
+func (t *StreamTextObject) endDest() {
+	tag := C.CString(tagDest)
+	C.cairo_tag_end(t.canvas, tag)
+	C.free(unsafe.Pointer(tag))
+}
+
+// outlineEntry is an entry of the outline of a PDF file that later entries may be under.
+type outlineEntry struct {
+	level int
+	id    C.int
+}
+
+// addOutline adds an entry with the given title to the outline of PDF output, under the
+// last entry of a lower level, that goes to the destination named anchor.
+func (t *StreamTextObject) addOutline(title string, level int, anchor string) {
+	if _, ok := t.writer.(*pdfPageWriter); !ok {
+		return
+	}
+	for len(t.outline) > 0 && t.outline[len(t.outline)-1].level >= level {
+		t.outline = t.outline[:len(t.outline)-1]
+	}
+	parent := C.int(C.CAIRO_PDF_OUTLINE_ROOT)
+	if len(t.outline) > 0 {
+		parent = t.outline[len(t.outline)-1].id
+	}
+	ctitle := C.CString(title)
+	attrs := C.CString("dest=" + tagString(anchor))
+	id := C.cairo_pdf_surface_add_outline(t.surface, parent, ctitle, attrs, 0)
+	C.free(unsafe.Pointer(attrs))
+	C.free(unsafe.Pointer(ctitle))
+	t.outline = append(t.outline, outlineEntry{level, id})
+}
+
+// plainText returns the plain text of a paragraph without the marks of notes and links,
+// or "" if its markup is invalid.
+func plainText(text string, markup bool) string {
+	pt, ok := parseParagraphText(text, markup)
+	if !ok {
+		return ""
+	}
+	C.pango_attr_list_unref(pt.attrs)
+	return strings.TrimSpace(strings.NewReplacer(NoteMark, "", LinkStart, "", LinkEnd, "").Replace(pt.text))
+}
@@ -0,0 +1,39 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestMarkedRanges(t *testing.T) {
+	a, b := Link{URI: "http://example.com/"}, Link{Dest: "intro"}
+	text := "See " + LinkStart + "this" + LinkEnd + " and " + LinkStart + "that" + LinkEnd + "."
+	ranges := markedRanges(text, []Link{a, b})
+	expected := []linkRange{linkRange{a, 4, 8}, linkRange{b, 13, 17}}
+	if len(ranges) != len(expected) {
+		t.Fatalf("got %d ranges", len(ranges))
+	}
+	for i, r := range expected {
+		if ranges[i] != r {
+			t.Errorf("range %d is %v", i, ranges[i])
+		}
+	}
+	if plain := linkMarkRemover.Replace(text); plain[4:8] != "this" || plain[13:17] != "that" {
+		t.Errorf("ranges do not match text %q", plain)
+	}
+}
+
+func TestLinkAttributes(t *testing.T) {
+	type data struct {
+		Link  Link
+		Attrs string
+	}
+	var testData []data = []data{
+		data{Link{URI: "http://example.com/?a=1"}, "rect=[1 2 3.5 4] uri='http://example.com/?a=1'"},
+		data{Link{Dest: `it's`}, `rect=[1 2 3.5 4] dest='it\'s'`},
+	}
+	for _, d := range testData {
+		if attrs := linkAttributes(d.Link, 1, 2, 3.5, 4); attrs != d.Attrs {
+			t.Errorf("linkAttributes(%v) was %q", d.Link, attrs)
+		}
+	}
+}
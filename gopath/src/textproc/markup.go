@@ -0,0 +1,86 @@
+package textproc
+
+/*
+#cgo pkg-config: pango
+#include <stdlib.h>
+#include <pango/pango.h>
+*/
+import "C"
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// MarkupError is an error in Pango markup.
+type MarkupError struct {
+	Message string
+	// Offset is the byte offset in the markup where the error was found,
+	// or -1 if it is not known.
+	Offset int
+}
+
+func (e *MarkupError) Error() string {
+	return e.Message
+}
+
+// markupPositionRE matches the position in a GMarkup error message.
+var markupPositionRE = regexp.MustCompile(`line (\d+) char (\d+)`)
+
+// markupPrefix is what pango_parse_markup wraps around markup that is not already wrapped.
+const markupPrefix = "<markup>"
+
+// markupOffset converts a line and character number, as reported by
+// pango_parse_markup, to a byte offset in markup. It returns -1 if the position
+// is out of range.
+func markupOffset(markup string, line, char int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		i := strings.Index(markup[offset:], "\n")
+		if i < 0 {
+			return -1
+		}
+		offset += i + 1
+	}
+	if offset == 0 && !strings.HasPrefix(markup, markupPrefix) {
+		char -= utf8.RuneCountInString(markupPrefix)
+	}
+	for ; char > 1 && offset < len(markup); char-- {
+		_, size := utf8.DecodeRuneInString(markup[offset:])
+		offset += size
+	}
+	if char > 1 {
+		return len(markup)
+	}
+	return offset
+}
+
+// ValidateMarkup checks that markup is valid Pango markup.
+// If it is not, it returns a *MarkupError.
+func ValidateMarkup(markup string) error {
+	var gerr *C.GError
+	cmarkup := C.CString(markup)
+	defer C.free(unsafe.Pointer(cmarkup))
+	if C.pango_parse_markup(cmarkup, -1, 0, nil, nil, nil, &gerr) != C.FALSE {
+		return nil
+	}
+	err := &MarkupError{Message: C.GoString(gerr.message), Offset: -1}
+	C.g_error_free(gerr)
+	if m := markupPositionRE.FindStringSubmatch(err.Message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		char, _ := strconv.Atoi(m[2])
+		err.Offset = markupOffset(markup, line, char)
+	}
+	return err
+}
+
+// markupEscaper escapes the characters that are special in Pango markup.
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeMarkup returns plain text as Pango markup.
+func escapeMarkup(text string) string {
+	return markupEscaper.Replace(text)
+}
@@ -0,0 +1,26 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestMarkupOffset(t *testing.T) {
+	type data struct {
+		Markup string
+		Line   int
+		Char   int
+		Offset int
+	}
+	testData := []data{data{"abc <b>def", 1, 9, 0},
+		data{"abc <b>def", 1, 13, 4},
+		data{"<markup>abc</b></markup>", 1, 12, 11},
+		data{"Жж <b>", 1, 12, 5},
+		data{"abc\nde <i>", 2, 4, 7},
+		data{"abc", 1, 20, 3},
+		data{"abc", 3, 1, -1}}
+	for _, d := range testData {
+		if offset := markupOffset(d.Markup, d.Line, d.Char); offset != d.Offset {
+			t.Errorf("offset of %d:%d in %q was %d", d.Line, d.Char, d.Markup, offset)
+		}
+	}
+}
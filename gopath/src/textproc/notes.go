@@ -0,0 +1,206 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#cgo pkg-config: pango pangocairo
+#include <cairo.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+*/
+import "C"
+
+import (
+	"strings"
+)
+
+// NoteMark marks the place of a footnote's reference in the text of a paragraph.
+const NoteMark = "\ufffc"
+
+// Footnote is a note set at the bottom of the column that refers to it.
+type Footnote struct {
+	// Mark is set as a superscript at the reference, and before the note.
+	Mark string
+	// Text is Pango markup if Markup is true, or else plain text.
+	Text   string
+	Markup bool
+}
+
+// noteScale is the size of the text of notes, relative to the text that refers to them.
+const noteScale = 0.8
+
+// placedNote is a footnote set in a column of the current page.
+type placedNote struct {
+	column int
+	par    paragraph
+	// props are those of the note, and skip the baseline skip of the text referring to it.
+	props TypesettingProps
+	skip  float64
+	// rtl is true if the note runs right to left.
+	rtl bool
+}
+
+// height returns the height of a note's lines.
+func (n placedNote) height() float64 {
+	return float64(n.par.lineCount()) * n.props.Baselineskip
+}
+
+// replaceNoteMarks replaces the note marks in markup with the marks of the notes, as superscripts.
+func replaceNoteMarks(markup string, notes []Footnote) string {
+	for _, note := range notes {
+		markup = strings.Replace(markup, NoteMark, "<sup>"+escapeMarkup(note.Mark)+"</sup>", 1)
+	}
+	return markup
+}
+
+// noteIndexes returns the byte indexes of the notes' references in plain text,
+// after the note marks have been replaced by the marks of the notes.
+func noteIndexes(text string, notes []Footnote) []int {
+	var indexes []int
+	for _, note := range notes {
+		i := strings.Index(text, NoteMark)
+		if i < 0 {
+			break
+		}
+		text = text[:i] + note.Mark + text[i+len(NoteMark):]
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// lineNotes returns the notes referred to by each line of a paragraph whose text,
+// before its note marks were replaced, was markup.
+func lineNotes(par paragraph, markup string, notes []Footnote) map[int][]Footnote {
+	pt, ok := parseParagraphText(markup, true)
+	if !ok {
+		return nil
+	}
+	C.pango_attr_list_unref(pt.attrs)
+	lines := make(map[int][]Footnote)
+	for i, index := range noteIndexes(pt.text, notes) {
+		line := par.lineOfIndex(index)
+		lines[line] = append(lines[line], notes[i])
+	}
+	return lines
+}
+
+// noteProps returns the properties of the text of notes referred to by text set with props.
+func noteProps(props TypesettingProps) TypesettingProps {
+	nprops := props
+	if props.textFontsize > 0 {
+		nprops.Fontsize = props.textFontsize
+		nprops.Baselineskip = props.textBaselineskip
+	}
+	nprops.Fontsize *= noteScale
+	nprops.Baselineskip *= noteScale
+	nprops.Indent = 0
+	nprops.LeftIndent = 0
+	nprops.Label = ""
+	nprops.Notes = nil
+	nprops.Links = nil
+	nprops.Anchor = ""
+	nprops.Markup = true
+	return nprops
+}
+
+// layoutNotes breaks notes into lines, to be set in the current column.
+func (t *StreamTextObject) layoutNotes(notes []Footnote, props TypesettingProps) []placedNote {
+	nprops := noteProps(props)
+	var placed []placedNote
+	for _, note := range notes {
+		text := note.Text
+		if !note.Markup {
+			text = escapeMarkup(text)
+		}
+		text = "<sup>" + escapeMarkup(note.Mark) + "</sup> " + text
+		placed = append(placed, placedNote{column: t.column, par: t.breakParagraph(text, nprops),
+			props: nprops, skip: props.Baselineskip, rtl: t.rightToLeft(text, nprops)})
+	}
+	return placed
+}
+
+// notesSpace returns the space taken by notes at the bottom of a column,
+// including the space above them for the separator rule if first is true.
+func notesSpace(notes []placedNote, first bool) float64 {
+	space := 0.0
+	for i, note := range notes {
+		if i == 0 && first {
+			space += note.skip
+		}
+		space += note.height()
+	}
+	return space
+}
+
+// addNotes adds notes to the bottom of the current column, if the line at t.y still
+// fits above them or the column is empty. It returns false if they were not added.
+func (t *StreamTextObject) addNotes(notes []Footnote, props TypesettingProps) bool {
+	placed := t.layoutNotes(notes, props)
+	space := notesSpace(placed, t.noteSpace == 0)
+	if t.y > props.LastBaseline()-t.noteSpace-space && !t.columnEmpty() {
+		for _, note := range placed {
+			note.par.free()
+		}
+		return false
+	}
+	t.notes = append(t.notes, placed...)
+	t.noteSpace += space
+	return true
+}
+
+// textProps returns props with the bottom margin raised above the notes of the current column.
+func (t *StreamTextObject) textProps(props TypesettingProps) TypesettingProps {
+	props.BottomMargin += t.noteSpace
+	return props
+}
+
+// writeNotes sets the notes of each column of the current page at its bottom,
+// under a short rule.
+func (t *StreamTextObject) writeNotes() {
+	left := t.notes
+	for column := 0; len(left) > 0; column++ {
+		var notes []placedNote
+		var rest []placedNote
+		for _, note := range left {
+			if note.column == column {
+				notes = append(notes, note)
+			} else {
+				rest = append(rest, note)
+			}
+		}
+		left = rest
+		if len(notes) == 0 {
+			continue
+		}
+		props := notes[0].props
+		x := props.LeftMargin + props.columnOffset(column)
+		top := props.LastBaseline() - notesSpace(notes, false)
+		rule := top - notes[0].skip/3
+		ruleX := x
+		if props.Direction == RightToLeft {
+			ruleX += props.ColumnWidth() * 2 / 3
+		}
+		t.setColor(props.RuleColor)
+		C.cairo_set_line_width(t.canvas, 0.5)
+		C.cairo_move_to(t.canvas, C.double(ruleX), C.double(rule))
+		C.cairo_line_to(t.canvas, C.double(ruleX+props.ColumnWidth()/3), C.double(rule))
+		C.cairo_stroke(t.canvas)
+		y := top
+		t.setColor(props.TextColor)
+		for _, note := range notes {
+			for i := 0; i < note.par.lineCount(); i++ {
+				y += note.props.Baselineskip
+				align := note.props.lineAlignment(i == note.par.lineCount()-1, note.rtl)
+				offset := lineOffset(align, note.rtl, note.props.TextWidth(), note.par.lineWidth(i), 0)
+				note.par.drawLine(t.canvas, i, x+offset, y)
+			}
+		}
+	}
+}
+
+// freeNotes frees the notes of the current page.
+func (t *StreamTextObject) freeNotes() {
+	for _, note := range t.notes {
+		note.par.free()
+	}
+	t.notes = nil
+}
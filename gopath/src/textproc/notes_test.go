@@ -0,0 +1,38 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestNoteMarks(t *testing.T) {
+	notes := []Footnote{Footnote{Mark: "1"}, Footnote{Mark: "12"}, Footnote{Mark: "*"}}
+	text := "A" + NoteMark + " b <i>c</i>" + NoteMark + NoteMark + "."
+	if s := replaceNoteMarks(text, notes); s != "A<sup>1</sup> b <i>c</i><sup>12</sup><sup>*</sup>." {
+		t.Errorf("replaceNoteMarks returned %q", s)
+	}
+	plain := "A" + NoteMark + " b c" + NoteMark + NoteMark + "."
+	indexes := noteIndexes(plain, notes)
+	expected := []int{1, 6, 8}
+	if len(indexes) != len(expected) {
+		t.Fatalf("noteIndexes returned %v", indexes)
+	}
+	for i, index := range expected {
+		if indexes[i] != index {
+			t.Errorf("note %d is at %d, want %d", i, indexes[i], index)
+		}
+	}
+}
+
+func TestNoteProps(t *testing.T) {
+	props := TypesettingProps{Fontsize: 10, Baselineskip: 15, Indent: 15}
+	nprops := noteProps(props)
+	if nprops.Fontsize != 8 || nprops.Baselineskip != 12 || nprops.Indent != 0 {
+		t.Errorf("notes of body text were set at %v on %v, indented %v", nprops.Fontsize, nprops.Baselineskip, nprops.Indent)
+	}
+	heading := props
+	heading.textFontsize, heading.textBaselineskip = props.Fontsize, props.Baselineskip
+	heading.Fontsize, heading.Baselineskip = 17.28, 25.92
+	if hprops := noteProps(heading); hprops.Fontsize != nprops.Fontsize || hprops.Baselineskip != nprops.Baselineskip {
+		t.Errorf("notes of a heading were set at %v on %v", hprops.Fontsize, hprops.Baselineskip)
+	}
+}
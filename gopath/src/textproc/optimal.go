@@ -0,0 +1,286 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#cgo pkg-config: pango pangocairo
+#include <stdlib.h>
+#include <cairo.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+
+void gopango_attr_list_copy_range(PangoAttrList *dest, PangoAttrList *list, int start, int end, int offset);
+*/
+import "C"
+
+import (
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// optimalParagraph is a paragraph broken into lines by the Knuth-Plass algorithm.
+// Each line is a list of segments, each set separately at its own position.
+type optimalParagraph struct {
+	lines [][]lineSegment
+	// starts are the byte indexes in the paragraph's text of the starts of the lines.
+	starts []int
+}
+
+// lineSegment is a run of text with no glue in it, set at an offset from the start of its line.
+// ranges are the byte ranges of the paragraph's text in it.
+type lineSegment struct {
+	layout *C.PangoLayout
+	x      float64
+	ranges [][2]int
+}
+
+func (p *optimalParagraph) lineCount() int {
+	return len(p.lines)
+}
+
+func (p *optimalParagraph) lineOfIndex(index int) int {
+	line := 0
+	for i, start := range p.starts {
+		if start <= index {
+			line = i
+		}
+	}
+	return line
+}
+
+func (p *optimalParagraph) drawLine(cr *C.cairo_t, i int, x float64, y float64) {
+	for _, segment := range p.lines[i] {
+		C.cairo_move_to(cr, C.double(x+segment.x), C.double(y))
+		C.pango_cairo_show_layout_line(cr, C.pango_layout_get_line(segment.layout, 0))
+	}
+}
+
+func (p *optimalParagraph) indexX(i int, index int) float64 {
+	for _, segment := range p.lines[i] {
+		local := 0
+		for _, r := range segment.ranges {
+			if index <= r[1] {
+				if index > r[0] {
+					local += index - r[0]
+				}
+				var x C.int
+				C.pango_layout_line_index_to_x(C.pango_layout_get_line(segment.layout, 0), C.int(local), C.FALSE, &x)
+				return segment.x + float64(x)/C.PANGO_SCALE
+			}
+			local += r[1] - r[0]
+		}
+	}
+	return p.lineWidth(i)
+}
+
+func (p *optimalParagraph) lineWidth(i int) float64 {
+	line := p.lines[i]
+	if len(line) == 0 {
+		return 0
+	}
+	last := line[len(line)-1]
+	return last.x + layoutWidth(last.layout)
+}
+
+func (p *optimalParagraph) free() {
+	for _, line := range p.lines {
+		for _, segment := range line {
+			C.g_object_unref(C.gpointer(segment.layout))
+		}
+	}
+	p.lines = nil
+}
+
+// paragraphText is the plain text of a paragraph, and the Pango attributes from its markup.
+type paragraphText struct {
+	text  string
+	attrs *C.PangoAttrList
+}
+
+// parseParagraphText returns the plain text and attributes of a paragraph.
+// The caller must release the attributes with pango_attr_list_unref.
+// ok is false if the markup is invalid.
+func parseParagraphText(text string, markup bool) (pt paragraphText, ok bool) {
+	if !markup {
+		return paragraphText{text: text, attrs: C.pango_attr_list_new()}, true
+	}
+	var ctext *C.char
+	cmarkup := C.CString(text)
+	defer C.free(unsafe.Pointer(cmarkup))
+	if C.pango_parse_markup(cmarkup, -1, 0, &pt.attrs, &ctext, nil, nil) == C.FALSE {
+		return pt, false
+	}
+	pt.text = C.GoString(ctext)
+	C.g_free(C.gpointer(ctext))
+	return pt, true
+}
+
+// layoutWidth returns the width of the first line of a layout.
+func layoutWidth(layout *C.PangoLayout) float64 {
+	var logical C.PangoRectangle
+	C.pango_layout_line_get_extents(C.pango_layout_get_line(layout, 0), nil, &logical)
+	return float64(logical.width) / C.PANGO_SCALE
+}
+
+// textWidth returns the width of plain text set in the font of props.
+func (t *StreamTextObject) textWidth(text string, props TypesettingProps) float64 {
+	props.Markup = false
+	layout := t.makeLayout(text, props, -1)
+	defer C.g_object_unref(C.gpointer(layout))
+	return layoutWidth(layout)
+}
+
+// segmentLayout returns an unwrapped layout of the byte ranges of a paragraph's text,
+// run together with their attributes, and followed by a hyphen if hyphen is true.
+// The caller must release it with g_object_unref.
+func (t *StreamTextObject) segmentLayout(pt paragraphText, ranges [][2]int, hyphen bool, props TypesettingProps) *C.PangoLayout {
+	attrs := C.pango_attr_list_new()
+	defer C.pango_attr_list_unref(attrs)
+	text := ""
+	for _, r := range ranges {
+		C.gopango_attr_list_copy_range(attrs, pt.attrs, C.int(r[0]), C.int(r[1]), C.int(len(text)))
+		text += pt.text[r[0]:r[1]]
+	}
+	if hyphen && len(ranges) > 0 {
+		// The hyphen takes the attributes of the letter before it.
+		last := ranges[len(ranges)-1]
+		_, size := utf8.DecodeLastRuneInString(pt.text[last[0]:last[1]])
+		C.gopango_attr_list_copy_range(attrs, pt.attrs, C.int(last[1]-size), C.int(last[1]), C.int(len(text)))
+		text += "-"
+	}
+	props.Markup = false
+	layout := t.makeLayout(text, props, -1)
+	props.setAttributes(layout, attrs)
+	return layout
+}
+
+// paragraphItems returns the boxes, glue and penalties of a paragraph.
+// Spaces become glue, and soft hyphens and explicit hyphens become penalties.
+// Line separators and newlines force breaks.
+func (t *StreamTextObject) paragraphItems(pt paragraphText, props TypesettingProps) []breakItem {
+	space := t.textWidth(" ", props)
+	hyphenWidth := t.textWidth("-", props)
+	glue := breakItem{kind: glueItem, width: space, stretch: space / 2, shrink: space / 3}
+	var items []breakItem
+	if props.Indent != 0 {
+		items = append(items, breakItem{kind: boxItem, width: props.Indent, start: -1, end: -1})
+	}
+	text := pt.text
+	start := 0
+	box := func(end int) {
+		if end > start {
+			layout := t.segmentLayout(pt, [][2]int{[2]int{start, end}}, false, props)
+			items = append(items, breakItem{kind: boxItem, width: layoutWidth(layout), start: start, end: end})
+			C.g_object_unref(C.gpointer(layout))
+		}
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+		switch {
+		case r == '\n' || r == '\u2028':
+			box(i)
+			items = append(items, breakItem{kind: glueItem, stretch: fillStretch, start: i, end: next},
+				breakItem{kind: penaltyItem, penalty: -infinitePenalty, start: next, end: next})
+			start = next
+		case unicode.IsSpace(r) && r != '\u00a0':
+			box(i)
+			if n := len(items); n == 0 || items[n-1].kind != glueItem {
+				g := glue
+				g.start, g.end = i, next
+				items = append(items, g)
+			}
+			start = next
+		case r == '\u00ad':
+			box(i)
+			items = append(items, breakItem{kind: penaltyItem, width: hyphenWidth, penalty: props.HyphenPenalty,
+				flagged: true, start: i, end: next})
+			start = next
+		case r == '-' && i > start && next < len(text) && text[next] != ' ':
+			box(next)
+			items = append(items, breakItem{kind: penaltyItem, penalty: props.ExHyphenPenalty,
+				flagged: true, start: next, end: next})
+			start = next
+		}
+		i = next
+	}
+	box(len(text))
+	items = append(items, breakItem{kind: glueItem, stretch: fillStretch, start: len(text), end: len(text)},
+		breakItem{kind: penaltyItem, penalty: -infinitePenalty, start: len(text), end: len(text)})
+	return items
+}
+
+// optimalParagraph breaks text into lines with the Knuth-Plass algorithm, and
+// sets the words of each line itself. It returns nil if the paragraph cannot be
+// broken within the tolerance.
+func (t *StreamTextObject) optimalParagraph(text string, props TypesettingProps) paragraph {
+	pt, ok := parseParagraphText(text, props.Markup)
+	if !ok {
+		return nil
+	}
+	defer C.pango_attr_list_unref(pt.attrs)
+	items := t.paragraphItems(pt, props)
+	params := defaultBreakParams(props.Tolerance, props.Looseness)
+	breaks := breakLines(items, props.TextWidth(), params)
+	if breaks == nil {
+		return nil
+	}
+
+	p := &optimalParagraph{}
+	start := 0
+	for n, b := range breaks {
+		// Glue and penalties at the start of a line are discarded.
+		for start < b.position && items[start].kind != boxItem {
+			start++
+		}
+		var line []lineSegment
+		var ranges [][2]int
+		lineStart := -1
+		x, segmentX := 0.0, 0.0
+		if n == 0 {
+			// The indent of the first line is left to the caller, as with Pango.
+			x = -props.Indent
+		}
+		ratio := b.ratio
+		switch {
+		case n == len(breaks)-1 && props.justifyLastLine():
+			ratio = fillRatio(items[start:b.position], props.TextWidth())
+		case !props.justified() && ratio > 0:
+			// Ragged lines are not stretched, though they may have to be shrunk.
+			ratio = 0
+		}
+		flush := func(hyphen bool) {
+			if len(ranges) > 0 {
+				line = append(line, lineSegment{t.segmentLayout(pt, ranges, hyphen, props), segmentX, ranges})
+				ranges = nil
+			}
+		}
+		for _, item := range items[start:b.position] {
+			switch item.kind {
+			case boxItem:
+				if item.start >= 0 {
+					if lineStart < 0 {
+						lineStart = item.start
+					}
+					if len(ranges) == 0 {
+						segmentX = x
+					}
+					ranges = append(ranges, [2]int{item.start, item.end})
+				}
+				x += item.width
+			case glueItem:
+				flush(false)
+				x += adjustedGlue(item, ratio)
+			}
+		}
+		end := items[b.position]
+		flush(end.kind == penaltyItem && end.flagged && end.width > 0)
+		if lineStart < 0 {
+			lineStart = items[b.position].start
+		}
+		p.lines = append(p.lines, line)
+		p.starts = append(p.starts, lineStart)
+		start = b.position + 1
+	}
+	return p
+}
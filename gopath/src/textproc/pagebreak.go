@@ -0,0 +1,54 @@
+package textproc
+
+import (
+	"math"
+)
+
+// linesThatFit returns the number of lines, Baselineskip apart, that fit in a column
+// with the first on the baseline y.
+func linesThatFit(y float64, props TypesettingProps) int {
+	if y > props.LastBaseline() {
+		return 0
+	}
+	if props.Baselineskip <= 0 {
+		return math.MaxInt32
+	}
+	return int((props.LastBaseline()-y)/props.Baselineskip) + 1
+}
+
+// columnBreak chooses how many of the lines of a paragraph, from line start of n,
+// to set in the current column, which has room for room lines. If empty is true,
+// nothing has been set in the column yet, so at least one line must be.
+//
+// Each possible break costs the widow penalty if it leaves fewer than props.Widows
+// lines for the next column, and the orphan penalty if it leaves fewer than
+// props.Orphans lines of a paragraph's start at the bottom of this one. A break
+// also costs a badness of up to 100 for the space it leaves empty. Breaks with
+// a cost of infinitePenalty or more are not taken, unless there is no other.
+func columnBreak(start, n, room int, empty bool, props TypesettingProps) int {
+	remaining := n - start
+	if remaining <= room {
+		return remaining
+	}
+	min := 0
+	if empty {
+		min = 1
+	}
+	if room <= min {
+		return min
+	}
+	best, bestCost := room, math.Inf(1)
+	for k := room; k >= min; k-- {
+		cost := 100 * math.Pow(float64(room-k)/float64(room), 3)
+		if start == 0 && k > 0 && k < props.Orphans {
+			cost += props.OrphanPenalty
+		}
+		if rest := remaining - k; rest < props.Widows {
+			cost += props.WidowPenalty
+		}
+		if cost < infinitePenalty && cost < bestCost {
+			best, bestCost = k, cost
+		}
+	}
+	return best
+}
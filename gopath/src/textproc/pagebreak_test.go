@@ -0,0 +1,55 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestLinesThatFit(t *testing.T) {
+	props := TypesettingProps{Fontsize: 10, Baselineskip: 12, TopMargin: 50, BottomMargin: 50, PageHeight: 400}
+	type data struct {
+		Y     float64
+		Lines int
+	}
+	var testData []data = []data{data{350, 1},
+		data{351, 0},
+		data{338, 2},
+		data{60, 25},
+	}
+	for _, d := range testData {
+		if n := linesThatFit(d.Y, props); n != d.Lines {
+			t.Errorf("linesThatFit(%g) = %d, want %d", d.Y, n, d.Lines)
+		}
+	}
+}
+
+func TestColumnBreak(t *testing.T) {
+	props := TypesettingProps{Widows: 2, Orphans: 2, WidowPenalty: 150, OrphanPenalty: 150}
+	forbid := props
+	forbid.WidowPenalty = infinitePenalty
+	forbid.OrphanPenalty = infinitePenalty
+	type data struct {
+		Start, N, Room int
+		Empty          bool
+		Props          TypesettingProps
+		Lines          int
+	}
+	var testData []data = []data{data{0, 5, 10, false, props, 5},
+		// Leaving a widow: one line fewer goes in this column.
+		data{0, 11, 10, false, props, 9},
+		// Leaving an orphan: the paragraph starts in the next column.
+		data{0, 5, 1, false, props, 0},
+		// Both: the empty column is never left blank.
+		data{0, 3, 1, true, forbid, 1},
+		// Continuing a paragraph, at the top of a column.
+		data{20, 31, 10, true, props, 9},
+		data{20, 40, 10, true, props, 10},
+		// Penalties of zero allow any break.
+		data{0, 11, 10, false, TypesettingProps{}, 10},
+		data{0, 4, 3, false, forbid, 2},
+	}
+	for _, d := range testData {
+		if k := columnBreak(d.Start, d.N, d.Room, d.Empty, d.Props); k != d.Lines {
+			t.Errorf("columnBreak(%d, %d, %d, %v) = %d, want %d", d.Start, d.N, d.Room, d.Empty, k, d.Lines)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#include <stdlib.h>
+#include <cairo.h>
+#include <cairo-pdf.h>
+#include <cairo-ps.h>
+#include <cairo-svg.h>
+
+cairo_surface_t *gocairo_ps_surface_create_for_stream(void *closure, double width_in_points, double height_in_points);
+cairo_surface_t *gocairo_svg_surface_create_for_stream(void *closure, double width_in_points, double height_in_points);
+cairo_status_t gocairo_surface_write_to_png_stream(cairo_surface_t *surface, void *closure);
+*/
+import "C"
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+	"unsafe"
+)
+
+// pageWriter makes the surfaces that the pages of a text object are drawn on.
+// Text is always laid out on a PDF surface, so that it is broken into lines
+// and pages the same way whatever the pages are drawn on.
+type pageWriter interface {
+	// beginPage returns the context to draw page number page on, of width by
+	// height points, or nil if the page is not wanted.
+	beginPage(page int, width, height float64) *C.cairo_t
+	// endPage finishes the page begun last.
+	endPage()
+	// close finishes the output.
+	close()
+}
+
+// pdfPageWriter draws all pages on the PDF surface text is laid out with.
+type pdfPageWriter struct {
+	surface *C.cairo_surface_t
+	context *C.cairo_t
+}
+
+func (w *pdfPageWriter) beginPage(page int, width, height float64) *C.cairo_t {
+	C.cairo_pdf_surface_set_size(w.surface, C.double(width), C.double(height))
+	return w.context
+}
+
+func (w *pdfPageWriter) endPage() {
+	C.cairo_show_page(w.context)
+}
+
+func (w *pdfPageWriter) close() {}
+
+// Metadata is the document information of a PDF file. Empty fields are left out.
+type Metadata struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+	Created  time.Time
+	Modified time.Time
+}
+
+// pdfDate formats a time as cairo takes PDF dates, or returns "" for the zero time.
+func pdfDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
+}
+
+// SetMetadata sets the document information of PDF output. Other formats have none.
+func (t *StreamTextObject) SetMetadata(m Metadata) {
+	if _, ok := t.writer.(*pdfPageWriter); !ok {
+		return
+	}
+	fields := []struct {
+		key   C.cairo_pdf_metadata_t
+		value string
+	}{
+		{C.CAIRO_PDF_METADATA_TITLE, m.Title},
+		{C.CAIRO_PDF_METADATA_AUTHOR, m.Author},
+		{C.CAIRO_PDF_METADATA_SUBJECT, m.Subject},
+		{C.CAIRO_PDF_METADATA_KEYWORDS, m.Keywords},
+		{C.CAIRO_PDF_METADATA_CREATOR, m.Creator},
+		{C.CAIRO_PDF_METADATA_CREATE_DATE, pdfDate(m.Created)},
+		{C.CAIRO_PDF_METADATA_MOD_DATE, pdfDate(m.Modified)},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		value := C.CString(f.value)
+		C.cairo_pdf_surface_set_metadata(t.surface, f.key, value)
+		C.free(unsafe.Pointer(value))
+	}
+}
+
+// psPageWriter draws all pages on a PostScript surface of their own.
+type psPageWriter struct {
+	out     io.Writer
+	surface *C.cairo_surface_t
+	context *C.cairo_t
+}
+
+func (w *psPageWriter) beginPage(page int, width, height float64) *C.cairo_t {
+	C.cairo_ps_surface_set_size(w.surface, C.double(width), C.double(height))
+	return w.context
+}
+
+func (w *psPageWriter) endPage() {
+	C.cairo_show_page(w.context)
+}
+
+func (w *psPageWriter) close() {
+	C.cairo_destroy(w.context)
+	C.cairo_surface_finish(w.surface)
+	C.cairo_surface_destroy(w.surface)
+}
+
+// Page formats of a single page writer.
+const (
+	svgPage = iota
+	pngPage
+	epsPage
+)
+
+// singlePageWriter draws one page on a surface of its own, and writes it as SVG, PNG or EPS.
+type singlePageWriter struct {
+	out    io.Writer
+	format int
+	page   int
+	// scale is the number of pixels per point of a PNG image.
+	scale   float64
+	surface *C.cairo_surface_t
+	context *C.cairo_t
+	// err is the error that stopped the page being written, if there was one.
+	err error
+}
+
+func (w *singlePageWriter) beginPage(page int, width, height float64) *C.cairo_t {
+	if page != w.page {
+		return nil
+	}
+	switch w.format {
+	case svgPage:
+		w.surface = C.gocairo_svg_surface_create_for_stream(unsafe.Pointer(&w.out), C.double(width), C.double(height))
+		w.context = C.cairo_create(w.surface)
+	case pngPage:
+		if w.err = checkPNGSize(width*w.scale, height*w.scale); w.err != nil {
+			return nil
+		}
+		w.surface = C.cairo_image_surface_create(C.CAIRO_FORMAT_ARGB32,
+			C.int(width*w.scale+0.5), C.int(height*w.scale+0.5))
+		if C.cairo_surface_status(w.surface) != C.CAIRO_STATUS_SUCCESS {
+			C.cairo_surface_destroy(w.surface)
+			w.surface = nil
+			w.err = errors.New("Could not create page image")
+			return nil
+		}
+		w.context = C.cairo_create(w.surface)
+		C.cairo_set_source_rgb(w.context, 1.0, 1.0, 1.0)
+		C.cairo_paint(w.context)
+		C.cairo_scale(w.context, C.double(w.scale), C.double(w.scale))
+	case epsPage:
+		w.surface = C.gocairo_ps_surface_create_for_stream(unsafe.Pointer(&w.out), C.double(width), C.double(height))
+		C.cairo_ps_surface_set_eps(w.surface, 1)
+		w.context = C.cairo_create(w.surface)
+	}
+	return w.context
+}
+
+func (w *singlePageWriter) endPage() {
+	C.cairo_destroy(w.context)
+	if w.format == pngPage {
+		if C.gocairo_surface_write_to_png_stream(w.surface, unsafe.Pointer(&w.out)) != C.CAIRO_STATUS_SUCCESS {
+			w.err = errors.New("Could not write page image")
+		}
+	}
+	C.cairo_surface_finish(w.surface)
+	C.cairo_surface_destroy(w.surface)
+	w.context = nil
+	w.surface = nil
+}
+
+func (w *singlePageWriter) close() {}
+
+// MaxDPI is the highest resolution of PNG images of pages.
+const MaxDPI = 1200
+
+// MaxPNGSide and MaxPNGPixels are the largest width or height, and the most pixels,
+// of PNG images of pages, which are drawn whole in memory.
+const (
+	MaxPNGSide   = 10000
+	MaxPNGPixels = 40000000
+)
+
+// checkPNGSize checks that a PNG image of width by height pixels is not too large.
+func checkPNGSize(width, height float64) error {
+	if width > MaxPNGSide || height > MaxPNGSide || width*height > MaxPNGPixels {
+		return errors.New("Page images can be at most 10000 pixels wide and high, and 40 million pixels")
+	}
+	return nil
+}
+
+// CheckPNGPage checks that a PNG image of a page of width by height points,
+// at dpi pixels per inch, is not too large.
+func CheckPNGPage(dpi float64, width, height float64) error {
+	return checkPNGSize(width*dpi/72, height*dpi/72)
+}
+
+// PageSize is the size of a page, in points.
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+// PageSizes returns the sizes of the pages that have been started.
+func (t *StreamTextObject) PageSizes() []PageSize {
+	return append(append([]PageSize(nil), t.sizes...), PageSize{t.width, t.height})
+}
+
+// Err returns the error that stopped a page image being written, if there was one.
+func (t *StreamTextObject) Err() error {
+	if w, ok := t.writer.(*singlePageWriter); ok {
+		return w.err
+	}
+	return nil
+}
+
+// MakePSStreamTextObject returns a text object writing a PostScript file of all its pages.
+func MakePSStreamTextObject(writer io.Writer, width, height float64) *StreamTextObject {
+	w := &psPageWriter{out: writer}
+	w.surface = C.gocairo_ps_surface_create_for_stream(unsafe.Pointer(&w.out), C.double(width), C.double(height))
+	w.context = C.cairo_create(w.surface)
+	return makeStreamTextObject(ioutil.Discard, w, width, height)
+}
+
+// MakeEPSPageTextObject returns a text object writing page number page, counting from 1,
+// as an Encapsulated PostScript file. Nothing is written if the document has fewer pages.
+func MakeEPSPageTextObject(writer io.Writer, page int, width, height float64) *StreamTextObject {
+	return makeStreamTextObject(ioutil.Discard, &singlePageWriter{out: writer, format: epsPage, page: page},
+		width, height)
+}
+
+// MakeSVGPageTextObject returns a text object writing page number page, counting from 1,
+// as an SVG image. Nothing is written if the document has fewer pages.
+func MakeSVGPageTextObject(writer io.Writer, page int, width, height float64) *StreamTextObject {
+	return makeStreamTextObject(ioutil.Discard, &singlePageWriter{out: writer, format: svgPage, page: page},
+		width, height)
+}
+
+// MakePNGPageTextObject returns a text object writing page number page, counting from 1,
+// as a PNG image of dpi pixels per inch. Nothing is written if the document has fewer pages.
+func MakePNGPageTextObject(writer io.Writer, page int, dpi float64, width, height float64) (*StreamTextObject, error) {
+	if dpi <= 0 || dpi > MaxDPI {
+		return nil, errors.New("Resolution must be between 1 and 1200 dpi")
+	}
+	return makeStreamTextObject(ioutil.Discard, &singlePageWriter{out: writer, format: pngPage, page: page,
+		scale: dpi / 72}, width, height), nil
+}
@@ -0,0 +1,45 @@
+package textproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPDFDate(t *testing.T) {
+	type data struct {
+		Time time.Time
+		Date string
+	}
+	var testData []data = []data{
+		data{time.Time{}, ""},
+		data{time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC), "2012-03-04T05:06:07Z"},
+		data{time.Date(2012, 3, 4, 5, 6, 7, 0, time.FixedZone("", -5*3600)), "2012-03-04T05:06:07-05:00"},
+	}
+	for _, d := range testData {
+		if date := pdfDate(d.Time); date != d.Date {
+			t.Errorf("pdfDate(%v) was %q, not %q", d.Time, date, d.Date)
+		}
+	}
+}
+
+func TestCheckPNGPage(t *testing.T) {
+	type data struct {
+		DPI           float64
+		Width, Height float64
+		Ok            bool
+	}
+	// Letter pages are 612 by 792 points.
+	var testData []data = []data{
+		data{96, 612, 792, true},
+		data{600, 612, 792, true},
+		data{1200, 612, 792, false},
+		data{72, 10001, 10, false},
+		data{72, 10, 10000, true},
+		data{72, 7000, 7000, false},
+	}
+	for _, d := range testData {
+		if err := CheckPNGPage(d.DPI, d.Width, d.Height); (err == nil) != d.Ok {
+			t.Errorf("CheckPNGPage(%v, %v, %v) returned %v", d.DPI, d.Width, d.Height, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package textproc
+
+/*
+#cgo pkg-config: cairo
+#cgo pkg-config: pango pangocairo
+#include <cairo.h>
+#include <pango/pango.h>
+#include <pango/pangocairo.h>
+*/
+import "C"
+
+import (
+	"math"
+)
+
+// Watermark is text stamped on pages, such as "DRAFT". An empty Fontname is the
+// font of the text of the pages. The text is turned Angle degrees counterclockwise
+// about its middle, which Center places on a page of the given size, or which is
+// in the middle of the page if Center is nil. Pages says which pages to stamp, or
+// if it is nil, all of them are. Over sets it over the text rather than under it.
+type Watermark struct {
+	Text     string
+	Fontname string
+	Fontsize float64
+	Color    Color
+	Opacity  float64
+	Angle    float64
+	Center   func(width, height float64) (x, y float64)
+	Pages    func(page int) bool
+	Over     bool
+}
+
+// onPage returns true if a watermark is stamped on the given page.
+func (w Watermark) onPage(page int) bool {
+	return w.Pages == nil || w.Pages(page)
+}
+
+// center returns the position of the middle of a watermark on a page of the given size.
+func (w Watermark) center(width, height float64) (x, y float64) {
+	if w.Center == nil {
+		return width / 2, height / 2
+	}
+	return w.Center(width, height)
+}
+
+// SetWatermarks sets the watermarks of every page, in the font of props unless
+// they have their own. They are set on each page as it is finished.
+func (t *StreamTextObject) SetWatermarks(marks []Watermark, props TypesettingProps) {
+	t.watermarks = marks
+	t.watermarkProps = props
+}
+
+// writeWatermarks stamps the current page with those of its watermarks that go
+// over the text if over is true, or under it if it is false.
+func (t *StreamTextObject) writeWatermarks(over bool) {
+	for _, w := range t.watermarks {
+		if w.Over == over && w.onPage(t.pages) {
+			t.writeWatermark(w)
+		}
+	}
+}
+
+// writeWatermark stamps a watermark on the current page.
+func (t *StreamTextObject) writeWatermark(w Watermark) {
+	props := t.watermarkProps
+	props.Markup = false
+	props.Fontsize = w.Fontsize
+	if w.Fontname != "" {
+		props.Fontname = w.Fontname
+		props.FontWeight = 0
+		props.FontStyle = ""
+		props.FontStretch = ""
+		props.FontVariant = ""
+		props.FontFeatures = nil
+	}
+	layout := t.makeLayout(w.Text, props, -1)
+	defer C.g_object_unref(C.gpointer(layout))
+	var logical C.PangoRectangle
+	C.pango_layout_get_extents(layout, nil, &logical)
+	width := float64(logical.width) / C.PANGO_SCALE
+	height := float64(logical.height) / C.PANGO_SCALE
+	x, y := w.center(t.width, t.height)
+
+	C.cairo_save(t.canvas)
+	C.cairo_translate(t.canvas, C.double(x), C.double(y))
+	// Cairo turns clockwise, as y runs down the page.
+	C.cairo_rotate(t.canvas, C.double(-w.Angle*math.Pi/180))
+	C.cairo_set_source_rgba(t.canvas, C.double(w.Color.R), C.double(w.Color.G), C.double(w.Color.B),
+		C.double(w.Opacity))
+	C.cairo_move_to(t.canvas, C.double(-width/2), C.double(-height/2))
+	C.pango_cairo_show_layout(t.canvas, layout)
+	C.cairo_restore(t.canvas)
+}
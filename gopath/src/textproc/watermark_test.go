@@ -0,0 +1,27 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestWatermarkOnPage(t *testing.T) {
+	w := Watermark{Text: "DRAFT"}
+	if !w.onPage(1) || !w.onPage(100) {
+		t.Errorf("watermark without pages is not on every page")
+	}
+	w.Pages = func(page int) bool { return page%2 == 0 }
+	if w.onPage(1) || !w.onPage(2) {
+		t.Errorf("watermark is not on the pages it was given")
+	}
+}
+
+func TestWatermarkCenter(t *testing.T) {
+	w := Watermark{Text: "DRAFT"}
+	if x, y := w.center(612, 792); x != 306 || y != 396 {
+		t.Errorf("watermark without a center is centered at %g, %g", x, y)
+	}
+	w.Center = func(width, height float64) (x, y float64) { return width / 2, 36 }
+	if x, y := w.center(612, 792); x != 306 || y != 36 {
+		t.Errorf("watermark is centered at %g, %g", x, y)
+	}
+}
@@ -0,0 +1,9 @@
+#!/bin/sh
+# Replaces the bundled hyphenation patterns with the original files from CTAN,
+# with their licence headers, in the directory of this script.
+set -e
+cd "$(dirname "$0")"
+base=https://mirrors.ctan.org/language/hyph-utf8/tex/generic/hyph-utf8/patterns/tex
+for name in en-us de-1996 fr; do
+	curl -fsSL -o "hyph-$name.tex" "$base/hyph-$name.tex"
+done